@@ -8,7 +8,7 @@ import (
 )
 
 /**
- * Wait a few seconds.
+ * Wait a few seconds. The time is read from the clock of the tree.
  *
  * @module b3
  * @class Wait
@@ -42,8 +42,8 @@ func (this *Wait) Initialize(setting *BTNodeCfg) {
  * @param {Tick} tick A tick instance.
 **/
 func (this *Wait) OnOpen(tick *Tick) {
-	var startTime int64 = time.Now().UnixNano() / 1000000
-	tick.Blackboard.Set("startTime", startTime, tick.GetTree().GetID(), this.GetID())
+	var startTime int64 = tick.NowMilli()
+	tick.Blackboard.Set("startTime", startTime, tick.GetTree().GetID(), tick.GetNodeScope(this))
 }
 
 /**
@@ -53,12 +53,18 @@ func (this *Wait) OnOpen(tick *Tick) {
  * @return {Constant} A state constant.
 **/
 func (this *Wait) OnTick(tick *Tick) b3.Status {
-	var currTime int64 = time.Now().UnixNano() / 1000000
-	var startTime = tick.Blackboard.GetInt64("startTime", tick.GetTree().GetID(), this.GetID())
+	//调用方已放弃本次tick
+	if tick.IsCancelled() {
+		return tick.Fail(this, tick.GetContext().Err())
+	}
+	var currTime int64 = tick.NowMilli()
+	var startTime = tick.Blackboard.GetInt64("startTime", tick.GetTree().GetID(), tick.GetNodeScope(this))
 	//fmt.Println("wait:",this.GetTitle(),tick.GetLastSubTree(),"=>", currTime-startTime)
 	if currTime-startTime > this.endTime {
 		return b3.SUCCESS
 	}
 
+	//事件驱动时，到时间再tick
+	tick.WakeAt(time.UnixMilli(startTime + this.endTime + 1))
 	return b3.RUNNING
 }
@@ -15,7 +15,16 @@ type MemPriority struct {
  * @param {b3.Tick} tick A tick instance.
 **/
 func (this *MemPriority) OnOpen(tick *Tick) {
-	tick.Blackboard.Set("runningChild", 0, tick.GetTree().GetID(), this.GetID())
+	tick.Blackboard.Set("runningChild", 0, tick.GetTree().GetID(), tick.GetNodeScope(this))
+}
+
+/**
+ * Close method. Forgets the running child, also when the node is aborted.
+ * @method close
+ * @param {b3.Tick} tick A tick instance.
+**/
+func (this *MemPriority) OnClose(tick *Tick) {
+	tick.Blackboard.Set("runningChild", 0, tick.GetTree().GetID(), tick.GetNodeScope(this))
 }
 
 /**
@@ -25,13 +34,13 @@ func (this *MemPriority) OnOpen(tick *Tick) {
  * @return {Constant} A state constant.
 **/
 func (this *MemPriority) OnTick(tick *Tick) b3.Status {
-	var child = tick.Blackboard.GetInt("runningChild", tick.GetTree().GetID(), this.GetID())
+	var child = tick.Blackboard.GetInt("runningChild", tick.GetTree().GetID(), tick.GetNodeScope(this))
 	for i := child; i < this.GetChildCount(); i++ {
 		var status = this.GetChild(i).Execute(tick)
 
 		if status != b3.FAILURE {
 			if status == b3.RUNNING {
-				tick.Blackboard.Set("runningChild", i, tick.GetTree().GetID(), this.GetID())
+				tick.Blackboard.Set("runningChild", i, tick.GetTree().GetID(), tick.GetNodeScope(this))
 			}
 
 			return status
@@ -16,9 +16,10 @@ type Priority struct {
  * @return {Constant} A state constant.
 **/
 func (this *Priority) OnTick(tick *Tick) b3.Status {
-	for i := 0; i < this.GetChildCount(); i++ {
+	for i := tick.ResumeChild(this); i < this.GetChildCount(); i++ {
 		var status = this.GetChild(i).Execute(tick)
 		if status != b3.FAILURE {
+			tick.SuspendChild(this, i)
 			return status
 		}
 	}
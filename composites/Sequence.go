@@ -20,9 +20,10 @@ type Sequence struct {
 **/
 func (this *Sequence) OnTick(tick *Tick) b3.Status {
 	//fmt.Println("tick Sequence :", this.GetTitle())
-	for i := 0; i < this.GetChildCount(); i++ {
+	for i := tick.ResumeChild(this); i < this.GetChildCount(); i++ {
 		var status = this.GetChild(i).Execute(tick)
 		if status != b3.SUCCESS {
+			tick.SuspendChild(this, i)
 			return status
 		}
 	}
@@ -12,6 +12,8 @@ type BTProjectCfg struct {
 	Select string                 `json:"selectedTree"`
 	Scope        string                 `json:"scope"`
 	Trees       []BTTreeCfg   `json:"trees"`
+	//工程属性，可以声明黑板key，见core.ParseSchema
+	Properties map[string]interface{} `json:"properties"`
 }
 
 //加载
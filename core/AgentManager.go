@@ -0,0 +1,329 @@
+package core
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	b3 "github.com/magicsea/behavior3go"
+)
+
+/**
+ * An agent of `AgentManager`: a tree ticked for a target with its own
+ * blackboard, every `interval` of the clock of the manager.
+ *
+ * @module b3
+ * @class Agent
+**/
+type Agent struct {
+	tree       *BehaviorTree
+	target     interface{}
+	blackboard *Blackboard
+	reactive   *ReactiveAgent
+
+	mutex    sync.Mutex
+	interval time.Duration
+	nextTick time.Time
+	lastTick time.Time
+	status   b3.Status
+}
+
+func (this *Agent) GetTree() *BehaviorTree {
+	return this.tree
+}
+
+func (this *Agent) GetTarget() interface{} {
+	return this.target
+}
+
+func (this *Agent) GetBlackboard() *Blackboard {
+	return this.blackboard
+}
+
+/**
+ * Sets the time between two ticks of the agent, 0 ticks it at every
+ * update. Far or invisible agents can be ticked less often.
+**/
+func (this *Agent) SetInterval(interval time.Duration) {
+	this.mutex.Lock()
+	this.interval = interval
+	this.mutex.Unlock()
+}
+
+func (this *Agent) GetInterval() time.Duration {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	return this.interval
+}
+
+//最后一次tick的状态和时间
+func (this *Agent) GetStatus() (b3.Status, time.Time) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	return this.status, this.lastTick
+}
+
+//间隔已到，事件驱动的agent还要需要tick
+func (this *Agent) isDue(now time.Time) bool {
+	this.mutex.Lock()
+	var due = !now.Before(this.nextTick)
+	this.mutex.Unlock()
+	return due && (this.reactive == nil || this.reactive.NeedsTick())
+}
+
+//返回是否tick了树，事件驱动的agent可能跳过
+func (this *Agent) tick(ctx context.Context, now time.Time) bool {
+	var status b3.Status
+	var ticked = true
+	if this.reactive != nil {
+		status, ticked = this.reactive.Update()
+	} else {
+		status = this.tree.TickContext(ctx, this.target, this.blackboard)
+	}
+
+	this.mutex.Lock()
+	if ticked {
+		this.status = status
+		this.lastTick = now
+	}
+	this.nextTick = now.Add(this.interval)
+	this.mutex.Unlock()
+	return ticked
+}
+
+/**
+ * AgentManager ticks many agents on a pool of goroutines.
+ *
+ * Each call to `Update` is a frame: the agents whose interval elapsed are
+ * ticked, each by a single goroutine, so an agent never runs concurrently
+ * with itself. The trees can be shared by agents, `BehaviorTree` keeps
+ * its state in the blackboards. Nodes writing to memory shared between
+ * agents must use a blackboard made with `NewSyncBlackboard`.
+ *
+ * With a frame budget, the manager stops starting ticks once the budget
+ * is spent. The agents left are ticked first at the next frame, so every
+ * agent gets its turn. Reactive agents with nothing to do are skipped and
+ * take no turn.
+ *
+ *     var manager = b3.NewAgentManager(4)
+ *     manager.SetBudget(2 * time.Millisecond)
+ *     manager.Add(tree, npc, b3.NewBlackboard())
+ *     // every frame
+ *     manager.Update()
+ *
+ * @module b3
+ * @class AgentManager
+**/
+type AgentManager struct {
+	mutex       sync.Mutex
+	update      sync.Mutex
+	agents      []*Agent
+	cursor      int
+	workers     int
+	budget      time.Duration
+	budgetClock Clock
+	clock       Clock
+}
+
+/**
+ * Creates a manager ticking on `workers` goroutines, at least one.
+**/
+func NewAgentManager(workers int) *AgentManager {
+	if workers < 1 {
+		workers = 1
+	}
+	return &AgentManager{workers: workers, clock: WallClock{}, budgetClock: WallClock{}}
+}
+
+/**
+ * Sets the time a frame may spend starting ticks, 0 for no limit. It is
+ * wall time unless set otherwise with `SetBudgetClock`.
+ * A tick which is started always completes, so a frame can overrun the
+ * budget by the longest tick.
+**/
+func (this *AgentManager) SetBudget(budget time.Duration) {
+	this.mutex.Lock()
+	this.budget = budget
+	this.mutex.Unlock()
+}
+
+/**
+ * Sets the clock measuring the frame budget, `WallClock` by default, e.g.
+ * a clock of the CPU time of the process.
+**/
+func (this *AgentManager) SetBudgetClock(clock Clock) {
+	if clock == nil {
+		clock = WallClock{}
+	}
+	this.mutex.Lock()
+	this.budgetClock = clock
+	this.mutex.Unlock()
+}
+
+/**
+ * Sets the clock of the tick intervals, `WallClock` by default.
+**/
+func (this *AgentManager) SetClock(clock Clock) {
+	if clock == nil {
+		clock = WallClock{}
+	}
+	this.mutex.Lock()
+	this.clock = clock
+	this.mutex.Unlock()
+}
+
+func (this *AgentManager) SetWorkers(workers int) {
+	if workers < 1 {
+		workers = 1
+	}
+	this.mutex.Lock()
+	this.workers = workers
+	this.mutex.Unlock()
+}
+
+//添加agent，每帧tick
+func (this *AgentManager) Add(tree *BehaviorTree, target interface{}, blackboard *Blackboard) *Agent {
+	if blackboard == nil {
+		panic("The blackboard parameter is obligatory and must be an instance of b3.Blackboard")
+	}
+	return this.add(&Agent{tree: tree, target: target, blackboard: blackboard})
+}
+
+//添加事件驱动的agent，只在需要时tick
+func (this *AgentManager) AddReactive(reactive *ReactiveAgent) *Agent {
+	return this.add(&Agent{tree: reactive.tree, target: reactive.target, blackboard: reactive.blackboard, reactive: reactive})
+}
+
+func (this *AgentManager) add(agent *Agent) *Agent {
+	this.mutex.Lock()
+	this.agents = append(this.agents, agent)
+	this.mutex.Unlock()
+	return agent
+}
+
+//移除agent，正在进行的帧中可能还会tick一次
+func (this *AgentManager) Remove(agent *Agent) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	for i, a := range this.agents {
+		if a != agent {
+			continue
+		}
+		this.agents = append(this.agents[:i:i], this.agents[i+1:]...)
+		if this.cursor > i {
+			this.cursor--
+		}
+		if this.cursor >= len(this.agents) {
+			this.cursor = 0
+		}
+		return
+	}
+}
+
+func (this *AgentManager) GetAgentCount() int {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	return len(this.agents)
+}
+
+/**
+ * Runs a frame, see `UpdateContext`.
+**/
+func (this *AgentManager) Update() (ticked int, deferred int) {
+	return this.UpdateContext(context.Background())
+}
+
+/**
+ * Runs a frame: ticks the agents which are due, with `ctx` as the context
+ * of their ticks. Returns how many agents were ticked and how many due
+ * agents were deferred to the next frame because the budget was spent.
+ * A reactive agent which had nothing to do when its turn came is in
+ * neither count.
+ * Frames do not overlap, a call waits for the previous one.
+ *
+ * @method UpdateContext
+ * @param {context.Context} ctx The context of the ticks.
+ * @return {Integer} The number of ticked agents.
+ * @return {Integer} The number of deferred agents.
+**/
+func (this *AgentManager) UpdateContext(ctx context.Context) (ticked int, deferred int) {
+	this.update.Lock()
+	defer this.update.Unlock()
+
+	this.mutex.Lock()
+	var agents = this.agents
+	var start = this.cursor
+	var workers = this.workers
+	var budget = this.budget
+	var clock = this.clock
+	var budgetClock = this.budgetClock
+	this.mutex.Unlock()
+
+	var now = clock.Now()
+	var due = make([]*Agent, 0, len(agents))
+	var positions = make([]int, 0, len(agents))
+	for i := 0; i < len(agents); i++ {
+		var pos = (start + i) % len(agents)
+		if agents[pos].isDue(now) {
+			due = append(due, agents[pos])
+			positions = append(positions, pos)
+		}
+	}
+	if len(due) == 0 {
+		return 0, 0
+	}
+
+	var deadline time.Time
+	if budget > 0 {
+		deadline = budgetClock.Now().Add(budget)
+	}
+
+	var next, count int64
+	var work = func() {
+		for {
+			if !deadline.IsZero() && !budgetClock.Now().Before(deadline) {
+				return
+			}
+			if ctx.Err() != nil {
+				return
+			}
+			var k = int(atomic.AddInt64(&next, 1) - 1)
+			if k >= len(due) {
+				return
+			}
+			if due[k].tick(ctx, now) {
+				atomic.AddInt64(&count, 1)
+			}
+		}
+	}
+
+	if workers == 1 || len(due) == 1 {
+		work()
+	} else {
+		var wg sync.WaitGroup
+		for i := 0; i < workers && i < len(due); i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				work()
+			}()
+		}
+		wg.Wait()
+	}
+
+	var started = b3.MinInt(int(atomic.LoadInt64(&next)), len(due))
+	ticked = int(atomic.LoadInt64(&count))
+	deferred = len(due) - started
+	if deferred > 0 {
+		// start with the first agent left behind at the next frame
+		this.mutex.Lock()
+		if len(this.agents) == len(agents) && positions[started] < len(this.agents) && this.agents[positions[started]] == due[started] {
+			this.cursor = positions[started]
+		} else {
+			this.cursor = 0
+		}
+		this.mutex.Unlock()
+	}
+	return ticked, deferred
+}
@@ -0,0 +1,111 @@
+package core_test
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/magicsea/behavior3go/core"
+)
+
+func TestAgentManagerInterval(t *testing.T) {
+	var tree = createCountTree()
+	var clock = NewManualClock(time.Unix(0, 0))
+	var manager = NewAgentManager(2)
+	manager.SetClock(clock)
+
+	var near, far = &npc{}, &npc{}
+	manager.Add(tree, near, NewBlackboard())
+	manager.Add(tree, far, NewBlackboard()).SetInterval(time.Second)
+
+	for i := 0; i < 10; i++ {
+		manager.Update()
+		clock.Advance(250 * time.Millisecond)
+	}
+	if near.ticks != 10 {
+		t.Error("near agent ticks:", near.ticks)
+	}
+	if far.ticks != 3 {
+		t.Error("far agent ticks:", far.ticks)
+	}
+}
+
+func TestAgentManagerBudget(t *testing.T) {
+	var tree = createCountTree()
+	var clock = NewManualClock(time.Unix(0, 0))
+	var manager = NewAgentManager(1)
+	manager.SetBudget(3 * time.Millisecond)
+	manager.SetBudgetClock(clock)
+
+	var npcs []*npc
+	for i := 0; i < 6; i++ {
+		var target = &npc{sleep: 2 * time.Millisecond, clock: clock}
+		npcs = append(npcs, target)
+		manager.Add(tree, target, NewBlackboard())
+	}
+
+	// two ticks per frame fit in the budget, every agent gets its turn
+	for i := 0; i < 3; i++ {
+		ticked, deferred := manager.Update()
+		if ticked != 2 || deferred != 4 {
+			t.Fatal("frame", i, "ticked", ticked, "deferred", deferred)
+		}
+	}
+	for i, target := range npcs {
+		if target.ticks != 1 {
+			t.Error("agent", i, "ticks:", target.ticks)
+		}
+	}
+}
+
+func TestAgentManagerReactive(t *testing.T) {
+	var tree = createCountTree()
+	var manager = NewAgentManager(1)
+	var target = &npc{}
+	var blackboard = NewBlackboard()
+	var reactive = NewReactiveAgent(tree, target, blackboard, "enemy")
+	defer reactive.Close()
+	manager.AddReactive(reactive)
+	manager.Add(tree, &npc{}, NewBlackboard())
+
+	if ticked, deferred := manager.Update(); ticked != 2 || deferred != 0 {
+		t.Error("ticked", ticked, "deferred", deferred)
+	}
+	// nothing changed, the reactive agent is skipped
+	if ticked, deferred := manager.Update(); ticked != 1 || deferred != 0 {
+		t.Error("ticked", ticked, "deferred", deferred)
+	}
+	blackboard.SetMem("enemy", 1)
+	if ticked, _ := manager.Update(); ticked != 2 || target.ticks != 2 {
+		t.Error("ticked", ticked, "reactive ticks", target.ticks)
+	}
+}
+
+func TestAgentManagerWorkers(t *testing.T) {
+	var tree = createCountTree()
+	tree.EnableStats(true)
+	var manager = NewAgentManager(8)
+
+	var npcs []*npc
+	var agents []*Agent
+	for i := 0; i < 100; i++ {
+		var target = &npc{}
+		npcs = append(npcs, target)
+		agents = append(agents, manager.Add(tree, target, NewBlackboard()))
+	}
+	for i := 0; i < 20; i++ {
+		if ticked, _ := manager.Update(); ticked != 100 {
+			t.Fatal("ticked", ticked)
+		}
+	}
+	for i, target := range npcs {
+		if target.ticks != 20 {
+			t.Error("agent", i, "ticks:", target.ticks)
+		}
+		if agents[i].GetBlackboard().GetInt("ticks", "", "") != 20 {
+			t.Error("agent", i, "blackboard ticks:", agents[i].GetBlackboard().GetInt("ticks", "", ""))
+		}
+	}
+	if stats := tree.GetStats(); stats.Ticks != 2000 || stats.Nodes["count"].Running != 2000 {
+		t.Error("stats", stats.Ticks, stats.Nodes["count"].Running)
+	}
+}
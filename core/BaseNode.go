@@ -2,6 +2,8 @@ package core
 
 import (
 	_ "fmt"
+	"runtime/debug"
+	"time"
 
 	b3 "github.com/magicsea/behavior3go"
 	. "github.com/magicsea/behavior3go/config"
@@ -13,7 +15,9 @@ type IBaseWrapper interface {
 	_open(tick *Tick)
 	_tick(tick *Tick) b3.Status
 	_close(tick *Tick)
+	_halt(tick *Tick)
 	_exit(tick *Tick)
+	_baseNode() *BaseNode
 }
 type IBaseNode interface {
 	IBaseWrapper
@@ -22,6 +26,7 @@ type IBaseNode interface {
 	Initialize(params *BTNodeCfg)
 	GetCategory() string
 	Execute(tick *Tick) b3.Status
+	GetID() string
 	GetName() string
 	GetTitle() string
 	SetBaseNodeWorker(worker IBaseWorker)
@@ -44,7 +49,9 @@ type IBaseNode interface {
  * BaseNode also provide 5 callback methods, which the node implementations
  * can override. They are `enter`, `open`, `tick`, `close` and `exit`. See
  * their documentation to know more. These callbacks are called inside the
- * `_execute` method, which is called in the tree traversal.
+ * `_execute` method, which is called in the tree traversal. A sixth one,
+ * `halt`, is called by `BehaviorTree` on running nodes that are
+ * interrupted.
  *
  * @module b3
  * @class BaseNode
@@ -179,36 +186,80 @@ func (this *BaseNode) GetTitle() string {
  * method calls all callbacks: `enter`, `open`, `tick`, `close`, and
  * `exit`. It only opens a node if it is not already open. In the same
  * way, this method only close a node if the node  returned a status
- * different of `b3.RUNNING`.
+ * different of `b3.RUNNING`. If the context of the tick is cancelled, the
+ * node is not executed at all and `b3.ERROR` is returned.
+ *
+ * Unless the panic policy of the tree is `PANIC_PROPAGATE`, a panic in the
+ * callbacks is recovered here and turned into a status.
  *
  * @method _execute
  * @param {Tick} tick A tick instance.
  * @return {Constant} The tick state.
  * @protected
 **/
-func (this *BaseNode) _execute(tick *Tick) b3.Status {
+func (this *BaseNode) _execute(tick *Tick) (status b3.Status) {
 	//fmt.Println("_execute :", this.title)
+	// the caller gave up, do not start new nodes
+	if tick.IsCancelled() {
+		return tick.Fail(this, tick.GetContext().Err())
+	}
+	// out of the time slice, the next tick resumes here
+	if tick._shouldSuspend() {
+		return b3.RUNNING
+	}
+
+	// RECOVER
+	if tick._panicPolicy() != PANIC_PROPAGATE {
+		var depth = tick._depth()
+		defer func() {
+			if r := recover(); r != nil {
+				status = tick._recovered(this, depth, r)
+			}
+		}()
+	}
+
 	// ENTER
 	this._enter(tick)
 
 	// OPEN
-	if !tick.Blackboard.GetBool("isOpen", tick.tree.id, this.id) {
+	if !tick.Blackboard.GetBool("isOpen", tick.tree.id, tick.GetNodeScope(this)) {
 		this._open(tick)
 	}
 
 	// TICK
-	var status = this._tick(tick)
+	status = this._tick(tick)
+	// the strict blackboard rejected a write of the node
+	if err := tick._takeRejected(); err != nil {
+		status = tick.Fail(this, err)
+	}
+	if status == b3.ERROR && tick._error == nil {
+		// the node did not tell why, at least report which node failed
+		tick.Fail(this, ErrNodeError)
+	} else if status != b3.ERROR {
+		// the node got over the errors of its children
+		tick._error = nil
+	}
 
 	// CLOSE
 	if status != b3.RUNNING {
+		tick._completed++
 		this._close(tick)
 	}
 
 	// EXIT
 	this._exit(tick)
+	// rejected while closing
+	if err := tick._takeRejected(); err != nil {
+		status = tick.Fail(this, err)
+	}
 
 	return status
 }
+//打开节点列表中保存的是BaseNode
+func (this *BaseNode) _baseNode() *BaseNode {
+	return this
+}
+
 func (this *BaseNode) Execute(tick *Tick) b3.Status {
 	return this._execute(tick)
 }
@@ -233,7 +284,7 @@ func (this *BaseNode) _enter(tick *Tick) {
 func (this *BaseNode) _open(tick *Tick) {
 	//fmt.Println("_open :", this.title)
 	tick._openNode(this)
-	tick.Blackboard.Set("isOpen", true, tick.tree.id, this.id)
+	tick.Blackboard.Set("isOpen", true, tick.tree.id, tick.GetNodeScope(this))
 	this.OnOpen(tick)
 }
 
@@ -246,8 +297,13 @@ func (this *BaseNode) _open(tick *Tick) {
 **/
 func (this *BaseNode) _tick(tick *Tick) b3.Status {
 	//fmt.Println("_tick :", this.title)
-	tick._tickNode(this)
-	return this.OnTick(tick)
+	if !tick.isObserved() {
+		return this.OnTick(tick)
+	}
+	var start = time.Now()
+	var status = this.OnTick(tick)
+	tick._tickNode(this, status, time.Since(start))
+	return status
 }
 
 /**
@@ -258,8 +314,30 @@ func (this *BaseNode) _tick(tick *Tick) b3.Status {
 **/
 func (this *BaseNode) _close(tick *Tick) {
 	tick._closeNode(this)
-	tick.Blackboard.Set("isOpen", false, tick.tree.id, this.id)
+	tick.Blackboard.Set("isOpen", false, tick.tree.id, tick.GetNodeScope(this))
 	this.OnClose(tick)
+	tick.Blackboard._compactNode(tick.tree.id, tick.GetNodeScope(this), this)
+}
+
+/**
+ * Wrapper for halt method. Halts a running node, then closes it.
+ * @method _halt
+ * @param {Tick} tick A tick instance.
+ * @protected
+**/
+func (this *BaseNode) _halt(tick *Tick) {
+	if tick._panicPolicy() != PANIC_PROPAGATE {
+		defer func() {
+			if r := recover(); r != nil {
+				// the node is closed anyway, keep the cause
+				tick.Blackboard.Set("isOpen", false, tick.tree.id, tick.GetNodeScope(this))
+				tick.Fail(this, &PanicError{Value: r, Stack: debug.Stack()})
+			}
+		}()
+	}
+	tick._haltNode(this)
+	this.OnHalt(tick)
+	this._close(tick)
 }
 
 /**
@@ -46,6 +46,17 @@ type IBaseWorker interface {
 	 * @param {Tick} tick A tick instance.
 	**/
 	OnClose(tick *Tick)
+	/**
+	 * Halt method, override this to use. This method is called when the node
+	 * is still `b3.RUNNING` but will not be ticked again: a higher priority
+	 * branch preempted it, the tree was aborted or the tick was cancelled.
+	 * `OnClose` is called right after it, so put here only what is specific
+	 * to an interruption (cancel a request, refund resources, ...).
+	 *
+	 * @method halt
+	 * @param {Tick} tick A tick instance.
+	**/
+	OnHalt(tick *Tick)
 	/**
 	 * Exit method, override this to use. Called every time in the end of the
 	 * execution.
@@ -107,6 +118,18 @@ func (this *BaseWorker) OnClose(tick *Tick) {
 
 }
 
+/**
+ * Halt method, override this to use. This method is called when the node
+ * is still `b3.RUNNING` but will not be ticked again, right before
+ * `OnClose`.
+ *
+ * @method halt
+ * @param {Tick} tick A tick instance.
+**/
+func (this *BaseWorker) OnHalt(tick *Tick) {
+
+}
+
 /**
  * Exit method, override this to use. Called every time in the end of the
  * execution.
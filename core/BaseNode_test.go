@@ -0,0 +1,45 @@
+package core_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	b3 "github.com/magicsea/behavior3go"
+	. "github.com/magicsea/behavior3go/config"
+	. "github.com/magicsea/behavior3go/core"
+)
+
+func TestHaltRunningChildOfDecorator(t *testing.T) {
+	var tree = createTestTree("timeout", "limit",
+		BTNodeCfg{Id: "limit", Name: "MaxTime", Category: b3.DECORATOR, Child: "run", Properties: map[string]interface{}{"maxTime": 100.0}},
+		BTNodeCfg{Id: "run", Name: "RunAction", Category: b3.ACTION},
+	)
+	var clock = NewManualClock(time.Unix(100, 0))
+	tree.SetClock(clock)
+	var log = &callLog{}
+	var blackboard = NewBlackboard()
+
+	if status := tree.Tick(log, blackboard); status != b3.RUNNING {
+		t.Fatal("status", status)
+	}
+	// the decorator gives up while its child is still running
+	clock.Advance(200 * time.Millisecond)
+	if status := tree.Tick(log, blackboard); status != b3.FAILURE {
+		t.Fatal("status", status)
+	}
+	if expected := (callLog{"run:open", "run:halt", "run:close"}); !reflect.DeepEqual(*log, expected) {
+		t.Error("calls:", *log)
+	}
+	if blackboard.GetBool("isOpen", tree.GetID(), "run") {
+		t.Error("child still open")
+	}
+
+	*log = nil
+	if status := tree.Tick(log, blackboard); status != b3.RUNNING {
+		t.Error("status", status)
+	}
+	if expected := (callLog{"run:open"}); !reflect.DeepEqual(*log, expected) {
+		t.Error("calls:", *log)
+	}
+}
@@ -1,7 +1,10 @@
 package core
 
 import (
+	"context"
 	"fmt"
+	"sync"
+	"time"
 
 	b3 "github.com/magicsea/behavior3go"
 	"github.com/magicsea/behavior3go/config"
@@ -112,7 +115,80 @@ type BehaviorTree struct {
 	**/
 	debug interface{}
 
+	/**
+	 * The clock of the time-based nodes. `WallClock` by default.
+	 * @property {Clock} clock
+	**/
+	clock Clock
+
+	/**
+	 * The observers notified of every tick, replaced on write.
+	 * @property {Array} observers
+	**/
+	observers     []TickObserver
+	observerMutex sync.Mutex
+
+	/**
+	 * What to do when a node panics. `PANIC_PROPAGATE` by default.
+	 * @property {PanicPolicy} panicPolicy
+	**/
+	panicPolicy  PanicPolicy
+	panicHandler PanicHandler
+	panicMutex   sync.Mutex
+
+	/**
+	 * The execution statistics, collected once enabled.
+	 * @property {statsCollector} stats
+	**/
+	stats        *statsCollector
+	statsEnabled bool
+	statsMutex   sync.Mutex
+
 	dumpInfo *config.BTTreeCfg
+
+	/**
+	 * The nodes of the tree by ID, set by `Load`.
+	 * @property {Object} nodes
+	**/
+	nodes map[string]IBaseNode
+
+	/**
+	 * The blackboard keys declared in the properties of the tree and its
+	 * project, set by `Load`.
+	 * @property {Schema} schema
+	**/
+	schema *Schema
+
+	/**
+	 * Finds the trees of the SubTree nodes, by the name of the node. The
+	 * global function of `SetSubTreeLoadFunc` if nil.
+	 * @property {Function} subTreeLoader
+	**/
+	subTreeLoader func(string) *BehaviorTree
+
+	/**
+	 * The maximum number of nested SubTree nodes, `DEFAULT_MAX_SUBTREE_DEPTH`
+	 * if 0.
+	 * @property {Integer} maxSubTreeDepth
+	**/
+	maxSubTreeDepth int
+}
+
+/**
+ * The options of a single tick, see `BehaviorTree.TickWith`.
+ *
+ * - **Context** The context of the tick, `context.Background()` if nil.
+ * - **Observers** Observers of this tick only, notified after the ones
+ *   registered on the tree.
+ * - **Waker** Told by the nodes when the tree must be ticked again, for
+ *   event-driven drivers, see `ReactiveAgent`.
+**/
+type TickOptions struct {
+	Context   context.Context
+	Observers []TickObserver
+	Waker     Waker
+	//时间片，用完后挂起遍历，0为不限
+	Budget time.Duration
 }
 
 func NewBeTree() *BehaviorTree {
@@ -133,6 +209,7 @@ func (this *BehaviorTree) Initialize() {
 	this.properties = make(map[string]interface{})
 	this.root = nil
 	this.debug = nil
+	this.clock = WallClock{}
 }
 
 func (this *BehaviorTree) GetID() string {
@@ -143,10 +220,118 @@ func (this *BehaviorTree) GetTitile() string {
 	return this.title
 }
 
+/**
+ * Sets the debug instance. If it implements `TickObserver`, it is notified
+ * of every tick like the observers added with `AddObserver`.
+**/
 func (this *BehaviorTree) SetDebug(debug interface{}) {
 	this.debug = debug
 }
 
+/**
+ * Registers an observer notified of every tick of this tree, whatever the
+ * target and blackboard. Safe to call while the tree is ticked.
+**/
+func (this *BehaviorTree) AddObserver(observer TickObserver) {
+	this.observerMutex.Lock()
+	defer this.observerMutex.Unlock()
+	var observers = make([]TickObserver, 0, len(this.observers)+1)
+	observers = append(observers, this.observers...)
+	this.observers = append(observers, observer)
+}
+
+func (this *BehaviorTree) RemoveObserver(observer TickObserver) {
+	this.observerMutex.Lock()
+	defer this.observerMutex.Unlock()
+	var observers = make([]TickObserver, 0, len(this.observers))
+	for _, o := range this.observers {
+		if o != observer {
+			observers = append(observers, o)
+		}
+	}
+	this.observers = observers
+}
+
+//当前的observer列表，包括实现了TickObserver的debug
+func (this *BehaviorTree) getObservers() []TickObserver {
+	this.observerMutex.Lock()
+	var observers = this.observers[:len(this.observers):len(this.observers)]
+	this.observerMutex.Unlock()
+
+	// appending must never write to the shared array
+	if debug, ok := this.debug.(TickObserver); ok {
+		observers = append(observers, debug)
+	}
+	return observers
+}
+
+/**
+ * Sets the clock read by the time-based nodes, nil restores `WallClock`.
+ * Use a `ManualClock` or a `FrameClock` to drive game time by hand.
+**/
+func (this *BehaviorTree) SetClock(clock Clock) {
+	if clock == nil {
+		clock = WallClock{}
+	}
+	this.clock = clock
+}
+
+func (this *BehaviorTree) GetClock() Clock {
+	return this.clock
+}
+
+/**
+ * Sets what happens when a node of the tree panics:
+ *
+ * - `PANIC_PROPAGATE` the panic goes up to the caller of `Tick` (default).
+ * - `PANIC_TO_FAILURE` the node returns `b3.FAILURE`.
+ * - `PANIC_TO_ERROR` the node returns `b3.ERROR` and the tick gets a
+ *   `*PanicError` with the stack, see `TickE`.
+ *
+ * The nodes opened by the panicking node are halted (`OnHalt` then
+ * `OnClose`), so the next tick starts them again.
+ *
+ * Safe to call while the tree is ticked, a tick keeps the policy it
+ * started with.
+**/
+func (this *BehaviorTree) SetPanicPolicy(policy PanicPolicy) {
+	this.panicMutex.Lock()
+	defer this.panicMutex.Unlock()
+	if policy == PANIC_HANDLER && this.panicHandler == nil {
+		panic("BehaviorTree.SetPanicPolicy: PANIC_HANDLER needs SetPanicHandler")
+	}
+	this.panicPolicy = policy
+}
+
+/**
+ * Recovers the panics of the nodes and lets `handler` decide the status
+ * of the node. Sets the policy to `PANIC_HANDLER`, nil restores
+ * `PANIC_PROPAGATE`.
+**/
+func (this *BehaviorTree) SetPanicHandler(handler PanicHandler) {
+	this.panicMutex.Lock()
+	defer this.panicMutex.Unlock()
+	this.panicHandler = handler
+	if handler == nil {
+		this.panicPolicy = PANIC_PROPAGATE
+	} else {
+		this.panicPolicy = PANIC_HANDLER
+	}
+}
+
+func (this *BehaviorTree) GetPanicPolicy() PanicPolicy {
+	this.panicMutex.Lock()
+	defer this.panicMutex.Unlock()
+	return this.panicPolicy
+}
+
+//当前的panic策略和处理函数，tick开始时取一次
+func (this *BehaviorTree) getPanicPolicy() (PanicPolicy, PanicHandler) {
+	this.panicMutex.Lock()
+	defer this.panicMutex.Unlock()
+	return this.panicPolicy, this.panicHandler
+}
+
 func (this *BehaviorTree) GetRoot() IBaseNode {
 	return this.root
 }
@@ -180,6 +365,32 @@ func (this *BehaviorTree) GetRoot() IBaseNode {
  * @param {Object} [names] A namespace or dict containing custom nodes.
 **/
 func (this *BehaviorTree) Load(data *config.BTTreeCfg, maps *b3.RegisterStructMaps, extMaps *b3.RegisterStructMaps) {
+	this.LoadWithSchema(data, maps, extMaps, nil)
+}
+
+/**
+ * Same as `Load`, with the blackboard keys declared by the project. The
+ * keys declared in the properties of the tree are added to them, and if
+ * any key is declared, the node properties must refer to declared keys
+ * (see `Schema`), or it panics.
+ *
+ * @method LoadWithSchema
+ * @param {Object} data The data structure representing a Behavior Tree.
+ * @param {Object} [names] A namespace or dict containing custom nodes.
+ * @param {Schema} schema The keys declared by the project, may be nil.
+**/
+func (this *BehaviorTree) LoadWithSchema(data *config.BTTreeCfg, maps *b3.RegisterStructMaps, extMaps *b3.RegisterStructMaps, schema *Schema) {
+	treeSchema, err := ParseSchema(data.Properties)
+	if err != nil {
+		panic("BehaviorTree.load: " + err.Error())
+	}
+	this.schema = NewSchema().Merge(schema).Merge(treeSchema)
+	if !this.schema.IsEmpty() {
+		if err := this.schema.checkNodes(data); err != nil {
+			panic("BehaviorTree.load: " + err.Error())
+		}
+	}
+
 	this.title = data.Title             //|| this.title;
 	this.description = data.Description // || this.description;
 	this.properties = data.Properties   // || this.properties;
@@ -238,6 +449,46 @@ func (this *BehaviorTree) Load(data *config.BTTreeCfg, maps *b3.RegisterStructMa
 	}
 
 	this.root = nodes[data.Root]
+	this.nodes = nodes
+}
+
+/**
+ * The blackboard keys declared for the tree, empty if none.
+**/
+func (this *BehaviorTree) GetSchema() *Schema {
+	return this.schema
+}
+
+/**
+ * Finds a node of the tree by ID, looking into the subtrees too. Returns
+ * nil if not found.
+ *
+ * @method GetNode
+ * @param {String} id The node ID.
+ * @return {IBaseNode} The node.
+**/
+func (this *BehaviorTree) GetNode(id string) IBaseNode {
+	return this.findNode(id, make(map[*BehaviorTree]bool))
+}
+
+func (this *BehaviorTree) findNode(id string, visited map[*BehaviorTree]bool) IBaseNode {
+	if node, ok := this.nodes[id]; ok {
+		return node
+	}
+	visited[this] = true
+	for _, node := range this.nodes {
+		if _, ok := node.(*SubTree); !ok {
+			continue
+		}
+		var sTree = this.LoadSubTree(node.GetName())
+		if sTree == nil || visited[sTree] {
+			continue
+		}
+		if found := sTree.findNode(id, visited); found != nil {
+			return found
+		}
+	}
+	return nil
 }
 
 /**
@@ -267,8 +518,8 @@ func (this *BehaviorTree) dump() *config.BTTreeCfg {
  * target and the blackboard objects.
  *
  * Note: BehaviorTree stores a list of open nodes from last tick, if these
- * nodes weren't called after the current tick, this method will close them
- * automatically.
+ * nodes weren't called after the current tick, this method will halt them
+ * automatically (`OnHalt` then `OnClose`).
  *
  * @method tick
  * @param {Object} target A target object.
@@ -276,53 +527,248 @@ func (this *BehaviorTree) dump() *config.BTTreeCfg {
  * @return {Constant} The tick signal state.
 **/
 func (this *BehaviorTree) Tick(target interface{}, blackboard *Blackboard) b3.Status {
+	return this.TickContext(context.Background(), target, blackboard)
+}
+
+/**
+ * Same as `Tick`, but the tick carries the given context. Every node can
+ * read it with `tick.GetContext()`.
+ *
+ * Once the context is cancelled or its deadline passed, no new node is
+ * executed, all nodes still open are halted from leaf to root and the
+ * method returns `b3.ERROR`.
+ *
+ * @method TickContext
+ * @param {context.Context} ctx The context of the tick.
+ * @param {Object} target A target object.
+ * @param {Blackboard} blackboard An instance of blackboard object.
+ * @return {Constant} The tick signal state.
+**/
+func (this *BehaviorTree) TickContext(ctx context.Context, target interface{}, blackboard *Blackboard) b3.Status {
+	return this.TickWith(target, blackboard, TickOptions{Context: ctx})
+}
+
+/**
+ * Same as `Tick`, with the options of this tick only.
+ *
+ * @method TickWith
+ * @param {Object} target A target object.
+ * @param {Blackboard} blackboard An instance of blackboard object.
+ * @param {TickOptions} options The options of the tick.
+ * @return {Constant} The tick signal state.
+**/
+func (this *BehaviorTree) TickWith(target interface{}, blackboard *Blackboard, options TickOptions) b3.Status {
+	var state, _ = this.TickWithE(target, blackboard, options)
+	return state
+}
+
+/**
+ * Same as `Tick`, but also returns why the tree returned `b3.ERROR`: the
+ * `*NodeError` attached by the failing node with `tick.Fail`, or the
+ * error of the context if the tick was cancelled. The error is nil for
+ * any other status.
+ *
+ * @method TickE
+ * @param {Object} target A target object.
+ * @param {Blackboard} blackboard An instance of blackboard object.
+ * @return {Constant} The tick signal state.
+ * @return {error} The cause of `b3.ERROR`.
+**/
+func (this *BehaviorTree) TickE(target interface{}, blackboard *Blackboard) (b3.Status, error) {
+	return this.TickWithE(target, blackboard, TickOptions{})
+}
+
+/**
+ * Same as `TickWith`, but also returns the cause of `b3.ERROR`, see
+ * `TickE`.
+**/
+func (this *BehaviorTree) TickWithE(target interface{}, blackboard *Blackboard, options TickOptions) (b3.Status, error) {
 	if blackboard == nil {
 		panic("The blackboard parameter is obligatory and must be an instance of b3.Blackboard")
 	}
+	var ctx = options.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	blackboard._beginTick(this, target)
 
 	/* CREATE A TICK OBJECT */
-	var tick = NewTick()
-	tick.debug = this.debug
-	tick.target = target
-	tick.Blackboard = blackboard
-	tick.tree = this
+	var tick = this.newTick(ctx, target, blackboard)
+	tick._observers = append(tick._observers, options.Observers...)
+	tick.waker = options.Waker
+	if options.Budget > 0 {
+		tick.deadline = time.Now().Add(options.Budget)
+	}
+
+	tick._beginTick()
+	var state = this._tick(tick)
+	tick._endTick(state)
+	if state != b3.ERROR {
+		return state, nil
+	}
+	if err := tick.GetError(); err != nil {
+		return state, err
+	}
+	return state, ErrNodeError
+}
+
+func (this *BehaviorTree) _tick(tick *Tick) b3.Status {
+	var blackboard = tick.Blackboard
 
 	/* TICK NODE */
 	var state = this.root._execute(tick)
 
 	/* CLOSE NODES FROM LAST TICK, IF NEEDED */
-	var lastOpenNodes = blackboard._getTreeData(this.id).OpenNodes
+	var treeData = blackboard._getTreeData(this.id)
+	treeData.tree = this
+	treeData.TraversalDepth = tick._subTreeDepth
+	if tick._depthExceeded {
+		treeData.DepthLimitHits++
+	}
+	var lastOpenNodes = treeData.OpenNodes
 	var currOpenNodes []IBaseNode
 	currOpenNodes = append(currOpenNodes, tick._openNodes...)
+	tick._openNodes = nil
+
+	if tick.IsCancelled() {
+		// halt everything, the nodes left running will not be ticked again
+		var start = openPrefixLen(lastOpenNodes, currOpenNodes)
+		haltNodes(tick, lastOpenNodes[start:])
+		haltNodes(tick, currOpenNodes)
+		haltNodes(tick, treeData.SuspendedNodes)
+		this._clearSuspended(tick, treeData)
+
+		treeData.OpenNodes = make([]IBaseNode, 0)
+		blackboard.SetTree("nodeCount", tick._nodeCount, this.id)
+		return tick.Fail(this.root, tick.GetContext().Err())
+	}
+
+	// keep the nodes of the last complete traversal open until this one completes
+	if tick._suspended {
+		this._suspend(tick, treeData, currOpenNodes)
+		return state
+	}
+	if treeData.Suspended {
+		this._clearSuspended(tick, treeData)
+	}
 
 	l := len(lastOpenNodes)
 	if l == len(currOpenNodes) {
-		if l == 0 || lastOpenNodes[l-1] == currOpenNodes[l-1] {
+		if l == 0 || nodeScope(lastOpenNodes[l-1]) == nodeScope(currOpenNodes[l-1]) {
 			return state
 		}
 	}
 
 	// does not close if it is still open in this tick
-	var start = 0
-	for i := 0; i < b3.MinInt(len(lastOpenNodes), len(currOpenNodes)); i++ {
-		start = i
-		if lastOpenNodes[i] != currOpenNodes[i] {
-			break
-		}
-	}
+	var start = openPrefixLen(lastOpenNodes, currOpenNodes)
 
-	// close the nodes
-	for i := len(lastOpenNodes) - 1; i >= start; i-- {
-		lastOpenNodes[i]._close(tick)
-	}
+	// halt the nodes
+	haltNodes(tick, lastOpenNodes[start:])
 
 	/* POPULATE BLACKBOARD */
-	blackboard._getTreeData(this.id).OpenNodes = currOpenNodes
+	treeData.OpenNodes = currOpenNodes
 	blackboard.SetTree("nodeCount", tick._nodeCount, this.id)
 
 	return state
 }
 
+func (this *BehaviorTree) newTick(ctx context.Context, target interface{}, blackboard *Blackboard) *Tick {
+	var tick = NewTick()
+	tick.debug = this.debug
+	tick.target = target
+	tick.Blackboard = blackboard
+	tick.tree = this
+	tick.ctx = ctx
+	tick.clock = this.clock
+	tick._observers = this.getObservers()
+	tick._panic, tick._panicHandler = this.getPanicPolicy()
+	return tick
+}
+
+/**
+ * Interrupts whatever the tree is running for the given target and
+ * blackboard.
+ *
+ * The open nodes recorded in the blackboard by the last tick are halted
+ * from leaf to root, so their `OnHalt` and `OnClose` are called and memory
+ * composites forget their running child. The next tick starts from scratch.
+ *
+ * @method Abort
+ * @param {Object} target A target object.
+ * @param {Blackboard} blackboard An instance of blackboard object.
+**/
+func (this *BehaviorTree) Abort(target interface{}, blackboard *Blackboard) {
+	if blackboard == nil {
+		panic("The blackboard parameter is obligatory and must be an instance of b3.Blackboard")
+	}
+
+	var tick = this.newTick(context.Background(), target, blackboard)
+	var treeData = blackboard._getTreeData(this.id)
+	haltNodes(tick, treeData.SuspendedNodes)
+	haltNodes(tick, treeData.OpenNodes)
+	this._clearSuspended(tick, treeData)
+	treeData.OpenNodes = make([]IBaseNode, 0)
+}
+
+/**
+ * Interrupts only the subtree started by the SubTree node `subTreeID`.
+ *
+ * The SubTree node and all the open nodes below it are halted from leaf
+ * to root, the nodes above it are kept open, so the next tick enters the
+ * subtree again from its root. Returns false if the SubTree node is not
+ * open for this blackboard.
+ *
+ * @method AbortSubTree
+ * @param {Object} target A target object.
+ * @param {Blackboard} blackboard An instance of blackboard object.
+ * @param {String} subTreeID The id of the SubTree node.
+ * @return {Boolean} Whether the subtree was open.
+**/
+func (this *BehaviorTree) AbortSubTree(target interface{}, blackboard *Blackboard, subTreeID string) bool {
+	if blackboard == nil {
+		panic("The blackboard parameter is obligatory and must be an instance of b3.Blackboard")
+	}
+
+	var treeData = blackboard._getTreeData(this.id)
+	var tick = this.newTick(context.Background(), target, blackboard)
+	var found = this._abortSuspended(tick, treeData, subTreeID)
+	for i, node := range treeData.OpenNodes {
+		if isSubTreeNode(node, subTreeID) {
+			haltNodes(tick, treeData.OpenNodes[i:])
+			treeData.OpenNodes = treeData.OpenNodes[:i:i]
+			return true
+		}
+	}
+	return found
+}
+
+//是否为SubTree节点subTreeID，子树中的SubTree节点也可以用作用域指定
+func isSubTreeNode(node IBaseNode, subTreeID string) bool {
+	if _, ok := node.GetBaseNodeWorker().(*SubTree); !ok {
+		return false
+	}
+	return nodeScope(node) == subTreeID || node.GetID() == subTreeID
+}
+
+//两次tick的打开节点列表中，相同前缀的长度
+func openPrefixLen(lastOpenNodes, currOpenNodes []IBaseNode) int {
+	var n = b3.MinInt(len(lastOpenNodes), len(currOpenNodes))
+	for i := 0; i < n; i++ {
+		if nodeScope(lastOpenNodes[i]) != nodeScope(currOpenNodes[i]) {
+			return i
+		}
+	}
+	return n
+}
+
+//从叶子到根打断节点，已经关闭的节点（本次tick正常结束）跳过
+func haltNodes(tick *Tick, nodes []IBaseNode) {
+	for i := len(nodes) - 1; i >= 0; i-- {
+		tick._haltScoped(nodes[i])
+	}
+}
+
 func (this *BehaviorTree) Print() {
 	printNode(this.root, 0)
 }
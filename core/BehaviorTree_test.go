@@ -0,0 +1,119 @@
+package core_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	b3 "github.com/magicsea/behavior3go"
+	. "github.com/magicsea/behavior3go/config"
+	. "github.com/magicsea/behavior3go/core"
+)
+
+func createRunTree() *BehaviorTree {
+	return createTestTree("run", "seq",
+		BTNodeCfg{Id: "seq", Name: "MemSequence", Category: b3.COMPOSITE, Children: []string{"ok", "run"}},
+		BTNodeCfg{Id: "ok", Name: "Succeeder", Category: b3.ACTION},
+		BTNodeCfg{Id: "run", Name: "RunAction", Category: b3.ACTION},
+	)
+}
+
+func TestTickContextCancelled(t *testing.T) {
+	var tree = createRunTree()
+	var log = &callLog{}
+	var blackboard = NewBlackboard()
+	var ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+
+	if status := tree.TickContext(ctx, log, blackboard); status != b3.ERROR {
+		t.Error("status", status)
+	}
+	if len(*log) != 0 {
+		t.Error("nodes ran:", *log)
+	}
+}
+
+func TestTickContextHaltsOpenNodes(t *testing.T) {
+	var tree = createRunTree()
+	var log = &callLog{}
+	var blackboard = NewBlackboard()
+
+	if status := tree.Tick(log, blackboard); status != b3.RUNNING {
+		t.Fatal("status", status)
+	}
+	var ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+	if status := tree.TickContext(ctx, log, blackboard); status != b3.ERROR {
+		t.Error("status", status)
+	}
+	if expected := (callLog{"run:open", "run:halt", "run:close"}); !reflect.DeepEqual(*log, expected) {
+		t.Error("calls:", *log)
+	}
+	if blackboard.GetBool("isOpen", tree.GetID(), "seq") {
+		t.Error("sequence still open")
+	}
+
+	// the next tick starts again from the first child
+	*log = nil
+	if status := tree.Tick(log, blackboard); status != b3.RUNNING {
+		t.Error("status", status)
+	}
+	if expected := (callLog{"run:open"}); !reflect.DeepEqual(*log, expected) {
+		t.Error("calls:", *log)
+	}
+}
+
+func TestAbort(t *testing.T) {
+	var tree = createRunTree()
+	var log = &callLog{}
+	var blackboard = NewBlackboard()
+
+	tree.Tick(log, blackboard)
+	tree.Abort(log, blackboard)
+	if expected := (callLog{"run:open", "run:halt", "run:close"}); !reflect.DeepEqual(*log, expected) {
+		t.Error("calls:", *log)
+	}
+	if blackboard.GetBool("isOpen", tree.GetID(), "seq") {
+		t.Error("sequence still open")
+	}
+}
+
+func TestAbortSubTree(t *testing.T) {
+	defer useSubTrees(createRunTree())()
+	var tree = createTestTree("main", "root",
+		BTNodeCfg{Id: "root", Name: "MemSequence", Category: b3.COMPOSITE, Children: []string{"first", "job"}},
+		BTNodeCfg{Id: "first", Name: "Succeeder", Category: b3.ACTION},
+		subTreeNode("job", "run", nil),
+	)
+	var log = &callLog{}
+	var blackboard = NewBlackboard()
+
+	if status := tree.Tick(log, blackboard); status != b3.RUNNING {
+		t.Fatal("status", status)
+	}
+	// only SubTree nodes can be aborted
+	if tree.AbortSubTree(log, blackboard, "root") {
+		t.Error("aborted a composite")
+	}
+	if !tree.AbortSubTree(log, blackboard, "job") {
+		t.Fatal("running subtree not found")
+	}
+	if expected := (callLog{"run:open", "run:halt", "run:close"}); !reflect.DeepEqual(*log, expected) {
+		t.Error("calls:", *log)
+	}
+	if blackboard.GetBool("isOpen", tree.GetID(), "seq") {
+		t.Error("subtree still open")
+	}
+	// the main tree keeps its running child and enters the subtree again
+	if !blackboard.GetBool("isOpen", tree.GetID(), "root") || blackboard.GetInt("runningChild", tree.GetID(), "root") != 1 {
+		t.Error("main sequence closed")
+	}
+	if tree.AbortSubTree(log, blackboard, "job") {
+		t.Error("aborted twice")
+	}
+	*log = nil
+	tree.Tick(log, blackboard)
+	if expected := (callLog{"run:open"}); !reflect.DeepEqual(*log, expected) {
+		t.Error("calls:", *log)
+	}
+}
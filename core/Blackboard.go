@@ -1,8 +1,8 @@
 package core
 
 import (
-	"fmt"
-	"reflect"
+	"sync"
+	"time"
 )
 /**
  * The Blackboard is the memory structure required by `BehaviorTree` and its
@@ -33,6 +33,9 @@ import (
  *     blackboard.set('testKey', 'value', tree.id, node.id);
  *     var value = blackboard.get('testKey', tree.id, node.id);
  *
+ * The nodes use `tick.GetNodeScope(node)` as node scope, so a subtree used
+ * by several SubTree nodes has a memory per SubTree node.
+ *
  * Note: Internally, the blackboard store these memories in different
  * objects, being the global on `_baseMemory`, the per tree on `_treeMemory`
  * and the per node per tree dynamically create inside the per tree memory
@@ -46,47 +49,156 @@ import (
 type TreeData struct {
 	NodeMemory     *Memory
 	OpenNodes      []IBaseNode
+	//最后tick进入的子树最大深度
 	TraversalDepth int
 	TraversalCycle int
+	//SubTree节点超过深度限制（GetMaxSubTreeDepth）而失败的tick次数，一般由子树循环引用引起
+	DepthLimitHits int
+	//挂起的遍历，下次tick继续
+	Suspended      bool
+	SuspendedNodes []IBaseNode
+	//最后tick的树，用于快照
+	tree *BehaviorTree
 }
 
 func NewTreeData() *TreeData {
-	return &TreeData{NewMemory(), make([]IBaseNode, 0), 0, 0}
+	return &TreeData{NodeMemory: NewMemory(), OpenNodes: make([]IBaseNode, 0)}
 }
 
 //------------------------Memory-------------------------
 type Memory struct {
 	_memory map[string]interface{}
+	//有过期时间的key，见SetWithTTL
+	_expires map[string]time.Time
+	//同步黑板的内存才有锁
+	_mutex *sync.RWMutex
 }
 
 func NewMemory() *Memory {
-	return &Memory{make(map[string]interface{})}
+	return &Memory{_memory: make(map[string]interface{})}
+}
+
+//加锁的内存，用于NewSyncBlackboard
+func newSyncMemory() *Memory {
+	return &Memory{_memory: make(map[string]interface{}), _mutex: new(sync.RWMutex)}
 }
 
 func (this *Memory) Get(key string) interface{} {
+	if this._mutex != nil {
+		this._mutex.RLock()
+		defer this._mutex.RUnlock()
+	}
 	return this._memory[key]
 }
 func (this *Memory) Set(key string, val interface{}) {
+	if this._mutex != nil {
+		this._mutex.Lock()
+		defer this._mutex.Unlock()
+	}
 	this._memory[key] = val
+	delete(this._expires, key)
+}
+//查找，返回是否存在
+func (this *Memory) Lookup(key string) (interface{}, bool) {
+	if this._mutex != nil {
+		this._mutex.RLock()
+		defer this._mutex.RUnlock()
+	}
+	val, ok := this._memory[key]
+	return val, ok
 }
 func (this *Memory) Remove(key string) {
-	delete(this._memory,key)
+	if this._mutex != nil {
+		this._mutex.Lock()
+		defer this._mutex.Unlock()
+	}
+	delete(this._memory, key)
+	delete(this._expires, key)
+}
+
+//写入并返回旧值
+func (this *Memory) _swap(key string, val interface{}) interface{} {
+	if this._mutex != nil {
+		this._mutex.Lock()
+		defer this._mutex.Unlock()
+	}
+	var old = this._memory[key]
+	this._memory[key] = val
+	delete(this._expires, key)
+	return old
+}
+
+//删除并返回旧值
+func (this *Memory) _delete(key string) (interface{}, bool) {
+	if this._mutex != nil {
+		this._mutex.Lock()
+		defer this._mutex.Unlock()
+	}
+	var old, ok = this._memory[key]
+	if ok {
+		delete(this._memory, key)
+		delete(this._expires, key)
+	}
+	return old, ok
+}
+
+//复制所有的值
+func (this *Memory) _copy() map[string]interface{} {
+	if this._mutex != nil {
+		this._mutex.RLock()
+		defer this._mutex.RUnlock()
+	}
+	var values = make(map[string]interface{}, len(this._memory))
+	for key, value := range this._memory {
+		values[key] = value
+	}
+	return values
 }
 //------------------------TreeMemory-------------------------
 type TreeMemory struct {
 	*Memory
 	_treeData   *TreeData
 	_nodeMemory map[string]*Memory
+
+	//最后tick的时间，空闲的树被回收
+	_lastTick time.Time
 }
 
 func NewTreeMemory() *TreeMemory {
-	return &TreeMemory{NewMemory(), NewTreeData(), make(map[string]*Memory)}
+	return &TreeMemory{Memory: NewMemory(), _treeData: NewTreeData(), _nodeMemory: make(map[string]*Memory)}
+}
+
+func newSyncTreeMemory() *TreeMemory {
+	return &TreeMemory{Memory: newSyncMemory(), _treeData: NewTreeData(), _nodeMemory: make(map[string]*Memory)}
 }
 
 //------------------------Blackboard-------------------------
 type Blackboard struct {
 	_baseMemory *Memory
 	_treeMemory map[string]*TreeMemory
+
+	//订阅，按订阅顺序通知，修改时复制
+	_subscriptions   []*blackboardSubscription
+	_subscriptionSeq int
+
+	//父黑板，基础内存找不到时向上查找；有订阅时监听父黑板的修改
+	_parent        *Blackboard
+	_unwatchParent func()
+
+	//声明的key，严格模式下拒绝错误的写入
+	_schema *Schema
+	_strict bool
+
+	//过期时间的时钟，没有设置时用墙上时钟；有过期key的内存
+	_clock       Clock
+	_ttlMemories map[*Memory]ttlScope
+
+	//内存回收策略，下次清理空闲树的时间
+	_gc        GCPolicy
+	_nextSweep time.Time
+
+	//同步黑板保护_treeMemory、节点内存表、订阅和父黑板，各个Memory有自己的锁
+	_mutex *sync.RWMutex
 }
 
 func NewBlackboard() *Blackboard {
@@ -95,11 +207,42 @@ func NewBlackboard() *Blackboard {
 	return p
 }
 
+/**
+ * Creates a blackboard which can be read and written from several
+ * goroutines, e.g. the memory of a squad shared by the trees of its
+ * members. It has the same API as `NewBlackboard`, each memory (global,
+ * per tree, per node) has its own lock.
+ *
+ * Only the memories are safe: the state of a tree (its open nodes) must
+ * still not be ticked concurrently for the same tree and blackboard.
+ *
+ *     var squad = b3.NewSyncBlackboard()
+ *     // in the nodes of every member
+ *     squad.SetMem("target", enemyId)
+ *
+ * @method NewSyncBlackboard
+ * @return {Blackboard} A thread-safe blackboard.
+**/
+func NewSyncBlackboard() *Blackboard {
+	p := &Blackboard{_mutex: new(sync.RWMutex)}
+	p.Initialize()
+	return p
+}
+
 func (this *Blackboard) Initialize() {
-	this._baseMemory = NewMemory()
+	if this._mutex != nil {
+		this._baseMemory = newSyncMemory()
+	} else {
+		this._baseMemory = NewMemory()
+	}
 	this._treeMemory = make(map[string]*TreeMemory)
 }
 
+//是否可以并发使用
+func (this *Blackboard) IsSync() bool {
+	return this._mutex != nil
+}
+
 /**
  * Internal method to retrieve the tree context memory. If the memory does
  * not exist, this method creates it.
@@ -110,10 +253,26 @@ func (this *Blackboard) Initialize() {
  * @protected
 **/
 func (this *Blackboard) _getTreeMemory(treeScope string) *TreeMemory {
-	if _, ok := this._treeMemory[treeScope]; !ok {
-		this._treeMemory[treeScope] = NewTreeMemory()
+	if this._mutex == nil {
+		if _, ok := this._treeMemory[treeScope]; !ok {
+			this._treeMemory[treeScope] = NewTreeMemory()
+		}
+		return this._treeMemory[treeScope]
+	}
+
+	this._mutex.RLock()
+	treeMem, ok := this._treeMemory[treeScope]
+	this._mutex.RUnlock()
+	if ok {
+		return treeMem
 	}
-	return this._treeMemory[treeScope]
+	this._mutex.Lock()
+	defer this._mutex.Unlock()
+	if treeMem, ok = this._treeMemory[treeScope]; !ok {
+		treeMem = newSyncTreeMemory()
+		this._treeMemory[treeScope] = treeMem
+	}
+	return treeMem
 }
 
 /**
@@ -128,11 +287,26 @@ func (this *Blackboard) _getTreeMemory(treeScope string) *TreeMemory {
 **/
 func (this *Blackboard) _getNodeMemory(treeMemory *TreeMemory, nodeScope string) *Memory {
 	memory := treeMemory._nodeMemory
-	if _, ok := memory[nodeScope]; !ok {
-		memory[nodeScope] = NewMemory()
+	if this._mutex == nil {
+		if _, ok := memory[nodeScope]; !ok {
+			memory[nodeScope] = NewMemory()
+		}
+		return memory[nodeScope]
 	}
 
-	return memory[nodeScope]
+	this._mutex.RLock()
+	nodeMem, ok := memory[nodeScope]
+	this._mutex.RUnlock()
+	if ok {
+		return nodeMem
+	}
+	this._mutex.Lock()
+	defer this._mutex.Unlock()
+	if nodeMem, ok = memory[nodeScope]; !ok {
+		nodeMem = newSyncMemory()
+		memory[nodeScope] = nodeMem
+	}
+	return nodeMem
 }
 
 /**
@@ -180,22 +354,62 @@ func (this *Blackboard) _getMemory(treeScope, nodeScope string) *Memory {
  * @param {String} nodeScope The node id if accessing the node memory.
 **/
 func (this *Blackboard) Set(key string, value interface{}, treeScope, nodeScope string) {
+	this._setStrict(key, value, treeScope, nodeScope)
+}
+
+//同Set，返回严格模式拒绝写入的原因
+func (this *Blackboard) _setStrict(key string, value interface{}, treeScope, nodeScope string) error {
+	if err := this._checkStrict(key, value, treeScope, nodeScope); err != nil {
+		return err
+	}
+	this._set(key, value, treeScope, nodeScope)
+	return nil
+}
+
+func (this *Blackboard) _set(key string, value interface{}, treeScope, nodeScope string) {
 	var memory = this._getMemory(treeScope, nodeScope)
-	memory.Set(key, value)
+	var old = memory._swap(key, value)
+	this._written(key, old, value, false, treeScope, nodeScope)
 }
 
 func (this *Blackboard) SetMem(key string, value interface{}) {
-	var memory = this._getMemory("", "")
-	memory.Set(key, value)
+	this.Set(key, value, "", "")
 }
 
 func (this *Blackboard) Remove(key string) {
-	var memory = this._getMemory("", "")
-	memory.Remove(key)
+	this.Delete(key, "", "")
 }
 func (this *Blackboard) SetTree(key string, value interface{}, treeScope string) {
-	var memory = this._getMemory(treeScope, "")
-	memory.Set(key, value)
+	this.Set(key, value, treeScope, "")
+}
+
+/**
+ * Removes a key from the memory of the given scope, see `Set` for the
+ * scopes. `Remove` removes from the global memory.
+ *
+ * @method Delete
+ * @param {String} key The key to be removed.
+ * @param {String} treeScope The tree id if accessing the tree or node
+ *                           memory.
+ * @param {String} nodeScope The node id if accessing the node memory.
+**/
+func (this *Blackboard) Delete(key, treeScope, nodeScope string) {
+	var memory = this._getMemory(treeScope, nodeScope)
+	if old, ok := memory._delete(key); ok {
+		this._written(key, old, nil, true, treeScope, nodeScope)
+	}
+}
+
+func (this *Blackboard) _lock() {
+	if this._mutex != nil {
+		this._mutex.Lock()
+	}
+}
+
+func (this *Blackboard) _unlock() {
+	if this._mutex != nil {
+		this._mutex.Unlock()
+	}
 }
 func (this *Blackboard) _getTreeData(treeScope string) *TreeData {
 	treeMem := this._getTreeMemory(treeScope)
@@ -219,47 +433,50 @@ func (this *Blackboard) _getTreeData(treeScope string) *TreeData {
  * @return {Object} The value stored or undefined.
 **/
 func (this *Blackboard) Get(key, treeScope, nodeScope string) interface{} {
-	memory := this._getMemory(treeScope, nodeScope)
-	return memory.Get(key)
+	value, _ := this.Lookup(key, treeScope, nodeScope)
+	return value
 }
 func (this *Blackboard) GetMem(key string) interface{} {
-	memory := this._getMemory("","")
-	return memory.Get(key)
+	return this.Get(key, "", "")
 }
+/**
+ * The typed getters return 0 (false) if the key is not set. Numbers of
+ * any width are converted like a Go conversion, e.g. the float64 of a JSON
+ * config to an int, without checking overflow or fractional part, and a
+ * value which is not a number reads as 0, or false for `GetBool`. Use
+ * `Get` and `ToNumber` for a checked conversion.
+**/
 func (this *Blackboard) GetFloat64(key, treeScope, nodeScope string) float64 {
 	v := this.Get(key, treeScope, nodeScope)
 	if v == nil {
 		return 0
 	}
-	return v.(float64)
+	return castNumber[float64](v)
 }
 func (this *Blackboard) GetBool(key, treeScope, nodeScope string) bool {
-	v := this.Get(key, treeScope, nodeScope)
-	if v == nil {
-		return false
-	}
-	return v.(bool)
+	v, _ := Get[bool](this, key, treeScope, nodeScope)
+	return v
 }
 func (this *Blackboard) GetInt(key, treeScope, nodeScope string) int {
 	v := this.Get(key, treeScope, nodeScope)
 	if v == nil {
 		return 0
 	}
-	return v.(int)
+	return castNumber[int](v)
 }
 func (this *Blackboard) GetInt64(key, treeScope, nodeScope string) int64 {
 	v := this.Get(key, treeScope, nodeScope)
 	if v == nil {
 		return 0
 	}
-	return v.(int64)
+	return castNumber[int64](v)
 }
 func (this *Blackboard) GetUInt64(key, treeScope, nodeScope string) uint64 {
 	v := this.Get(key, treeScope, nodeScope)
 	if v == nil {
 		return 0
 	}
-	return v.(uint64)
+	return castNumber[uint64](v)
 }
 
 func (this *Blackboard) GetInt64Safe(key, treeScope, nodeScope string) int64 {
@@ -282,62 +499,15 @@ func (this *Blackboard) GetInt32(key, treeScope, nodeScope string) int32 {
 	if v == nil {
 		return 0
 	}
-	return v.(int32)
+	return castNumber[int32](v)
 }
 
-func ReadNumberToInt64(v interface{})  int64 {
-	var ret int64
-	switch tvalue := v.(type) {
-	case uint64:
-		ret = int64(tvalue)
-	default:
-		panic(fmt.Sprintf("错误的类型转成Int64 %v:%+v", reflect.TypeOf(v), v))
-	}
-
-	return ret
+//转成int64，支持所有数字类型，不检查溢出，不是数字时返回0，需要检查用ToNumber
+func ReadNumberToInt64(v interface{}) int64 {
+	return castNumber[int64](v)
 }
 
+//转成uint64，支持所有数字类型，不检查溢出，不是数字时返回0，需要检查用ToNumber
 func ReadNumberToUInt64(v interface{}) uint64 {
-	var ret uint64
-	switch tvalue := v.(type) {
-	case int64:
-		ret = uint64(tvalue)
-	default:
-		panic(fmt.Sprintf("错误的类型转成UInt64 %v:%+v", reflect.TypeOf(v), v))
-	}
-	return ret
-}
-//
-//func ReadNumberToInt32(v interface{}) int32 {
-//	var ret int32
-//	switch tvalue := v.(type) {
-//	case uint16, int16,uint32, int32,uint64,int64,uint16, int16,int:
-//		ret = int32(tvalue)
-//	default:
-//		panic(fmt.Sprintf("错误的类型转成Int32 %v:%+v", reflect.TypeOf(v), v))
-//	}
-//	return ret
-//}
-//
-//func ReadNumberToUInt32(v interface{}) uint32 {
-//	var ret uint32
-//	switch tvalue := v.(type) {
-//	case uint16, int16,uint32, int32,uint64,int64,uint16, int16,int:
-//		ret = uint32(tvalue)
-//	default:
-//		panic(fmt.Sprintf("错误的类型转成UInt32 %v:%+v", reflect.TypeOf(v), v))
-//	}
-//	return ret
-//}
-//
-//
-//func ReadNumberToInt(v interface{}) int {
-//	var ret int
-//	switch tvalue := v.(type) {
-//	case uint16, int16,uint32, int32,uint64,int64,uint16, int16,int:
-//		ret = int(tvalue)
-//	default:
-//		panic(fmt.Sprintf("int %v:%+v", reflect.TypeOf(v), v))
-//	}
-//	return ret
-//}
\ No newline at end of file
+	return castNumber[uint64](v)
+}
@@ -0,0 +1,156 @@
+package core
+
+import "time"
+
+/**
+ * Sets the parent of the blackboard, nil to remove it. The keys missing in
+ * the global memory are read from the parent, then its own parent, e.g.
+ * agent → squad → world, so the shared facts are written once in the
+ * squad or world blackboard. Writes stay in this blackboard, use `Assign`
+ * or write to the parent to change a shared key. The per tree and per node
+ * memories are never inherited.
+ *
+ * The subscribers of the blackboard (see `Subscribe`) also get the changes
+ * of the global memory of the parents, for the keys which are not set in
+ * this blackboard, e.g. a `ReactiveAgent` watching "night" is woken when
+ * the world blackboard changes it. While it has subscribers, the
+ * blackboard is referenced by its parent until unsubscribed or detached.
+ *
+ *     var world = b3.NewSyncBlackboard()
+ *     var squad = b3.NewSyncBlackboard()
+ *     squad.SetParent(world)
+ *     var agent = b3.NewBlackboard()
+ *     agent.SetParent(squad)
+ *     world.SetMem("night", true)
+ *     agent.GetMem("night") // true
+ *
+ * Panics if the parent is the blackboard itself or one of its children.
+ *
+ * @method SetParent
+ * @param {Blackboard} parent The parent blackboard.
+**/
+func (this *Blackboard) SetParent(parent *Blackboard) {
+	for p := parent; p != nil; p = p.GetParent() {
+		if p == this {
+			panic("Blackboard.SetParent: the parent is a child of the blackboard")
+		}
+	}
+	this._lock()
+	defer this._unlock()
+	if this._unwatchParent != nil {
+		this._unwatchParent()
+		this._unwatchParent = nil
+	}
+	this._parent = parent
+	this._watchParent()
+}
+
+func (this *Blackboard) GetParent() *Blackboard {
+	this._rlock()
+	defer this._runlock()
+	return this._parent
+}
+
+//有订阅时监听父黑板，没有订阅时取消，必须持有锁
+func (this *Blackboard) _watchParent() {
+	var watch = this._parent != nil && len(this._subscriptions) > 0
+	if watch == (this._unwatchParent != nil) {
+		return
+	}
+	if watch {
+		this._unwatchParent = this._parent.watch(this._parentChanged)
+	} else {
+		this._unwatchParent()
+		this._unwatchParent = nil
+	}
+}
+
+//父黑板基础内存的修改，本黑板没有设置的key转发给订阅
+func (this *Blackboard) _parentChanged(change *BlackboardChange) {
+	if len(change.TreeScope) > 0 {
+		return
+	}
+	var old, ok, expired = this._baseMemory._lookup(change.Key, this._now)
+	if expired {
+		this._expired(change.Key, old, "", "")
+	}
+	if ok {
+		return
+	}
+	this._notify(change)
+}
+
+//基础内存和父黑板中读得到的所有key，本黑板的值覆盖父黑板的
+func (this *Blackboard) _resolvedMemory() map[string]interface{} {
+	var values = make(map[string]interface{})
+	if parent := this.GetParent(); parent != nil {
+		values = parent._resolvedMemory()
+	}
+	for key, value := range this._baseMemory._copy() {
+		values[key] = value
+	}
+	return values
+}
+
+/**
+ * Retrieves a value like `Get`, and whether the key is set. Keys of the
+ * global memory are looked up in the parents too.
+ *
+ * @method Lookup
+ * @param {String} key The key to be retrieved.
+ * @param {String} treeScope The tree id if accessing the tree or node
+ *                           memory.
+ * @param {String} nodeScope The node id if accessing the node memory.
+ * @return {Object} The value stored.
+ * @return {Boolean} Whether the key is set.
+**/
+func (this *Blackboard) Lookup(key, treeScope, nodeScope string) (interface{}, bool) {
+	return this._lookupAt(key, treeScope, nodeScope, nil)
+}
+
+//同Lookup，过期时间按now判断，nil时用各个黑板的时钟
+func (this *Blackboard) _lookupAt(key, treeScope, nodeScope string, now func() time.Time) (interface{}, bool) {
+	var clock = now
+	if clock == nil {
+		clock = this._now
+	}
+	var value, ok, expired = this._getMemory(treeScope, nodeScope)._lookup(key, clock)
+	if expired {
+		this._expired(key, value, treeScope, nodeScope)
+		value = nil
+	}
+	if ok {
+		return value, ok
+	}
+	if parent := this.GetParent(); parent != nil && len(treeScope) == 0 {
+		if value, ok = parent._lookupAt(key, "", "", now); ok {
+			return value, ok
+		}
+	}
+	return this._default(key, treeScope, nodeScope)
+}
+
+/**
+ * Writes a key of the global memory in the blackboard which holds it: this
+ * one or the nearest parent where the key is set. If no blackboard holds
+ * the key, it is set in this one. Returns the blackboard written.
+ *
+ * @method Assign
+ * @param {String} key The key to be stored.
+ * @param {Object} value The value to be stored.
+ * @return {Blackboard} The blackboard which holds the key.
+**/
+func (this *Blackboard) Assign(key string, value interface{}) *Blackboard {
+	for b := this; b != nil; b = b.GetParent() {
+		var old, ok, expired = b._baseMemory._lookup(key, b._now)
+		if expired {
+			b._expired(key, old, "", "")
+		}
+		if ok {
+			b.SetMem(key, value)
+			return b
+		}
+	}
+	this.SetMem(key, value)
+	return this
+}
@@ -0,0 +1,243 @@
+package core_test
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+
+	b3 "github.com/magicsea/behavior3go"
+	. "github.com/magicsea/behavior3go/config"
+	. "github.com/magicsea/behavior3go/core"
+)
+
+//小队成员，squad为共享的黑板
+type member struct {
+	name  string
+	squad *Blackboard
+}
+
+type SquadAction struct {
+	Action
+}
+
+func (this *SquadAction) OnTick(tick *Tick) b3.Status {
+	var target = tick.GetTarget().(*member)
+	var treeID = tick.GetTree().GetID()
+	var count, _ = ToNumber[int](tick.GetMem("count"))
+	count++
+	tick.SetMem("count", count)
+
+	target.squad.SetMem(target.name, count)
+	target.squad.SetTree("last", target.name, treeID)
+	target.squad.Set("last", target.name, treeID, this.GetID())
+	target.squad.GetMem("leader")
+	target.squad.Get("last", treeID, this.GetID())
+	return b3.SUCCESS
+}
+
+func createSquadTree() *BehaviorTree {
+	return createTestTree("squad", "seq",
+		BTNodeCfg{Id: "seq", Name: "Sequence", Category: b3.COMPOSITE, Children: []string{"ok", "squad"}},
+		BTNodeCfg{Id: "ok", Name: "Succeeder", Category: b3.ACTION},
+		BTNodeCfg{Id: "squad", Name: "SquadAction", Category: b3.ACTION},
+	)
+}
+
+func TestSyncBlackboardSharedTree(t *testing.T) {
+	var tree = createSquadTree()
+	tree.EnableStats(true)
+	var squad = NewSyncBlackboard()
+	var recorder = NewTraceRecorder(tree, squad)
+	defer recorder.Stop()
+
+	const members, ticks = 16, 200
+	var wg sync.WaitGroup
+	for i := 0; i < members; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			var target = &member{name: fmt.Sprint("member", i), squad: squad}
+			var blackboard = NewBlackboard()
+			for j := 0; j < ticks; j++ {
+				if status := tree.Tick(target, blackboard); status != b3.SUCCESS {
+					t.Error(target.name, "status", status)
+					return
+				}
+			}
+		}(i)
+	}
+	// the leader writes while the members read
+	for j := 0; j < ticks; j++ {
+		squad.SetMem("leader", j)
+	}
+	wg.Wait()
+
+	for i := 0; i < members; i++ {
+		if count := squad.GetInt(fmt.Sprint("member", i), "", ""); count != ticks {
+			t.Error("member", i, "count:", count)
+		}
+	}
+	if squad.Get("last", tree.GetID(), "") == nil {
+		t.Error("tree memory not written")
+	}
+	if stats := tree.GetStats(); stats.Ticks != members*ticks {
+		t.Error("stats ticks:", stats.Ticks)
+	}
+}
+
+func TestSyncBlackboardWatchers(t *testing.T) {
+	var squad = NewSyncBlackboard()
+	var tree = createSquadTree()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				var agent = NewReactiveAgent(tree, nil, squad, "enemy")
+				agent.NeedsTick()
+				agent.Close()
+			}
+		}(i)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				squad.SetMem("enemy", j)
+				squad.Set("hp", j, fmt.Sprint("tree", i), "node")
+				squad.Remove("enemy")
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if !squad.IsSync() || NewBlackboard().IsSync() {
+		t.Error("IsSync")
+	}
+}
+
+func TestSubscribe(t *testing.T) {
+	var blackboard = NewBlackboard()
+	var changes []BlackboardChange
+	var record = func(change *BlackboardChange) {
+		changes = append(changes, *change)
+	}
+	var target = blackboard.Subscribe("target", "", "", record)
+	var node = blackboard.Subscribe("", "tree", "node", record)
+
+	blackboard.SetMem("target", 1)
+	blackboard.SetMem("target", 2)
+	blackboard.SetMem("hp", 10)
+	blackboard.SetTree("target", 3, "tree")
+	blackboard.Set("i", 1, "tree", "node")
+	blackboard.Delete("i", "tree", "node")
+	blackboard.Delete("i", "tree", "node")
+	blackboard.Remove("target")
+
+	var expected = []BlackboardChange{
+		{Key: "target", New: 1},
+		{Key: "target", Old: 1, New: 2},
+		{Key: "i", TreeScope: "tree", NodeScope: "node", New: 1},
+		{Key: "i", TreeScope: "tree", NodeScope: "node", Old: 1, Removed: true},
+		{Key: "target", Old: 2, Removed: true},
+	}
+	if len(changes) != len(expected) {
+		t.Fatal("changes:", changes)
+	}
+	for i := range expected {
+		if changes[i] != expected[i] {
+			t.Error("change", i, changes[i], "expected", expected[i])
+		}
+	}
+
+	if !blackboard.Unsubscribe(target) || blackboard.Unsubscribe(target) {
+		t.Error("Unsubscribe")
+	}
+	blackboard.Unsubscribe(node)
+	changes = nil
+	blackboard.SetMem("target", 4)
+	blackboard.Set("i", 2, "tree", "node")
+	if len(changes) != 0 {
+		t.Error("changes after unsubscribe:", changes)
+	}
+}
+
+func TestBlackboardParent(t *testing.T) {
+	var world = NewSyncBlackboard()
+	var squad = NewSyncBlackboard()
+	var agent = NewBlackboard()
+	squad.SetParent(world)
+	agent.SetParent(squad)
+
+	world.SetMem("night", true)
+	world.SetMem("alarm", 1)
+	squad.SetMem("alarm", 2)
+	world.SetTree("tree", 1, "tree")
+
+	if !agent.GetBool("night", "", "") {
+		t.Error("night not inherited")
+	}
+	if v := agent.GetInt("alarm", "", ""); v != 2 {
+		t.Error("alarm:", v)
+	}
+	if v, ok := agent.Lookup("tree", "tree", ""); ok {
+		t.Error("tree memory inherited:", v)
+	}
+	if _, ok := agent.Lookup("missing", "", ""); ok {
+		t.Error("missing key found")
+	}
+
+	// writes stay local, unless assigned
+	agent.SetMem("night", false)
+	if world.GetBool("night", "", "") != true || agent.GetBool("night", "", "") != false {
+		t.Error("SetMem wrote to the parent")
+	}
+	if holder := agent.Assign("alarm", 3); holder != squad || squad.GetInt("alarm", "", "") != 3 {
+		t.Error("Assign alarm")
+	}
+	if holder := agent.Assign("new", 1); holder != agent {
+		t.Error("Assign new key")
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("cycle accepted")
+		}
+	}()
+	world.SetParent(agent)
+}
+
+func TestBlackboardParentSubscribe(t *testing.T) {
+	var world = NewSyncBlackboard()
+	var squad = NewSyncBlackboard()
+	var agent = NewBlackboard()
+	squad.SetParent(world)
+	agent.SetParent(squad)
+	agent.SetMem("alarm", 0)
+
+	var keys []string
+	var id = agent.Subscribe("", "", "", func(change *BlackboardChange) {
+		keys = append(keys, change.Key)
+	})
+	world.SetMem("night", true)
+	squad.SetMem("target", 1)
+	// shadowed by the agent, or not in the global memory
+	world.SetMem("alarm", 1)
+	world.SetTree("night", false, "tree")
+	if !reflect.DeepEqual(keys, []string{"night", "target"}) {
+		t.Error("changes:", keys)
+	}
+
+	keys = nil
+	agent.Unsubscribe(id)
+	world.SetMem("night", false)
+	agent.SetParent(nil)
+	agent.Subscribe("", "", "", func(change *BlackboardChange) {
+		keys = append(keys, change.Key)
+	})
+	squad.SetMem("target", 2)
+	if len(keys) != 0 {
+		t.Error("changes after unsubscribe:", keys)
+	}
+}
@@ -0,0 +1,124 @@
+package core
+
+import (
+	"sync"
+	"time"
+)
+
+/**
+ * The Clock is the source of time for the time-based nodes (`Wait`,
+ * `MaxTime`, ...). A clock is set on `BehaviorTree` and handed to every
+ * node through `tick.GetClock()`, so a simulation can fast-forward or
+ * replay game time instead of reading the wall clock.
+ *
+ * @module b3
+ * @class Clock
+**/
+type Clock interface {
+	Now() time.Time
+}
+
+//------------------------WallClock-------------------------
+//墙上时钟，默认时钟
+type WallClock struct {
+}
+
+func (this WallClock) Now() time.Time {
+	return time.Now()
+}
+
+//------------------------ManualClock-------------------------
+//手动时钟，时间只在Set/Advance时改变
+type ManualClock struct {
+	mutex sync.RWMutex
+	now   time.Time
+}
+
+func NewManualClock(start time.Time) *ManualClock {
+	return &ManualClock{now: start}
+}
+
+func (this *ManualClock) Now() time.Time {
+	this.mutex.RLock()
+	defer this.mutex.RUnlock()
+	return this.now
+}
+
+func (this *ManualClock) Set(now time.Time) {
+	this.mutex.Lock()
+	this.now = now
+	this.mutex.Unlock()
+}
+
+func (this *ManualClock) Advance(d time.Duration) {
+	this.mutex.Lock()
+	this.now = this.now.Add(d)
+	this.mutex.Unlock()
+}
+
+//------------------------FrameClock-------------------------
+//帧时钟，每帧推进一个delta，时间为起始时间加上累计的delta
+type FrameClock struct {
+	mutex   sync.RWMutex
+	start   time.Time
+	elapsed time.Duration
+	delta   time.Duration
+	frame   uint64
+}
+
+/**
+ * Creates a frame clock starting at `start`, `Step` advances it by `delta`.
+**/
+func NewFrameClock(start time.Time, delta time.Duration) *FrameClock {
+	return &FrameClock{start: start, delta: delta}
+}
+
+func (this *FrameClock) Now() time.Time {
+	this.mutex.RLock()
+	defer this.mutex.RUnlock()
+	return this.start.Add(this.elapsed)
+}
+
+//推进一帧，使用固定delta
+func (this *FrameClock) Step() {
+	this.StepDelta(this.GetDelta())
+}
+
+//推进一帧，使用本帧的delta
+func (this *FrameClock) StepDelta(delta time.Duration) {
+	this.mutex.Lock()
+	this.elapsed += delta
+	this.frame++
+	this.mutex.Unlock()
+}
+
+func (this *FrameClock) SetDelta(delta time.Duration) {
+	this.mutex.Lock()
+	this.delta = delta
+	this.mutex.Unlock()
+}
+
+func (this *FrameClock) GetDelta() time.Duration {
+	this.mutex.RLock()
+	defer this.mutex.RUnlock()
+	return this.delta
+}
+
+//已推进的帧数
+func (this *FrameClock) GetFrame() uint64 {
+	this.mutex.RLock()
+	defer this.mutex.RUnlock()
+	return this.frame
+}
+
+//已推进的总时间
+func (this *FrameClock) GetElapsed() time.Duration {
+	this.mutex.RLock()
+	defer this.mutex.RUnlock()
+	return this.elapsed
+}
+
+//毫秒时间戳
+func nowMilli(clock Clock) int64 {
+	return clock.Now().UnixNano() / 1000000
+}
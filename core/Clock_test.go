@@ -0,0 +1,63 @@
+package core_test
+
+import (
+	"testing"
+	"time"
+
+	b3 "github.com/magicsea/behavior3go"
+	. "github.com/magicsea/behavior3go/config"
+	. "github.com/magicsea/behavior3go/core"
+)
+
+func TestManualClock(t *testing.T) {
+	var start = time.Unix(100, 0)
+	var clock = NewManualClock(start)
+	clock.Advance(time.Second)
+	if !clock.Now().Equal(start.Add(time.Second)) {
+		t.Error("advance:", clock.Now())
+	}
+	clock.Set(start)
+	if !clock.Now().Equal(start) {
+		t.Error("set:", clock.Now())
+	}
+}
+
+func TestFrameClock(t *testing.T) {
+	var start = time.Unix(100, 0)
+	var clock = NewFrameClock(start, 100*time.Millisecond)
+	clock.Step()
+	clock.StepDelta(50 * time.Millisecond)
+	clock.SetDelta(time.Second)
+	clock.Step()
+	if clock.GetFrame() != 3 || clock.GetElapsed() != 1150*time.Millisecond {
+		t.Error("frame", clock.GetFrame(), "elapsed", clock.GetElapsed())
+	}
+	if !clock.Now().Equal(start.Add(1150 * time.Millisecond)) {
+		t.Error("now:", clock.Now())
+	}
+}
+
+func TestWaitFrameClock(t *testing.T) {
+	var tree = createTestTree("wait", "wait",
+		BTNodeCfg{Id: "wait", Name: "Wait", Category: b3.ACTION, Properties: map[string]interface{}{"milliseconds": 1000.0}},
+	)
+	var clock = NewFrameClock(time.Unix(100, 0), 400*time.Millisecond)
+	tree.SetClock(clock)
+	var blackboard = NewBlackboard()
+
+	// the wait only sees game time, the frames take no wall time
+	for frame := 0; frame < 3; frame++ {
+		if status := tree.Tick(nil, blackboard); status != b3.RUNNING {
+			t.Fatal("frame", frame, "status", status)
+		}
+		clock.Step()
+	}
+	if status := tree.Tick(nil, blackboard); status != b3.SUCCESS {
+		t.Error("status", status)
+	}
+
+	tree.SetClock(nil)
+	if _, ok := tree.GetClock().(WallClock); !ok {
+		t.Error("nil clock:", tree.GetClock())
+	}
+}
@@ -0,0 +1,49 @@
+package core
+
+import (
+	"errors"
+	"strings"
+)
+
+var (
+	//装饰节点没有子节点
+	ErrNoChild = errors.New("decorator has no child")
+	//子树找不到
+	ErrSubTreeNotFound = errors.New("subtree not found")
+	//子树嵌套超过树的最大深度，一般是子树循环引用
+	ErrSubTreeDepth = errors.New("subtree depth limit exceeded")
+	//节点返回了ERROR，但没有给出错误
+	ErrNodeError = errors.New("node returned ERROR")
+)
+
+/**
+ * The error attached to a tick by a node which returned `b3.ERROR`, with
+ * the node and the SubTree nodes the traversal was in. Use `errors.Is` or
+ * `errors.As` on `Err` to get the root cause.
+ *
+ * @module b3
+ * @class NodeError
+**/
+type NodeError struct {
+	NodeID    string
+	NodeName  string
+	NodeTitle string
+	//SubTree节点ID，从外到内
+	SubTreePath []string
+	Err         error
+}
+
+func (this *NodeError) Error() string {
+	var s = "node " + this.NodeID
+	if len(this.NodeName) > 0 {
+		s += "(" + this.NodeName + ")"
+	}
+	if len(this.SubTreePath) > 0 {
+		s += " in subtree " + strings.Join(this.SubTreePath, "/")
+	}
+	return s + ": " + this.Err.Error()
+}
+
+func (this *NodeError) Unwrap() error {
+	return this.Err
+}
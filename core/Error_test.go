@@ -0,0 +1,93 @@
+package core_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	b3 "github.com/magicsea/behavior3go"
+	. "github.com/magicsea/behavior3go/config"
+	. "github.com/magicsea/behavior3go/core"
+)
+
+func TestTickENodeError(t *testing.T) {
+	defer useSubTrees(createTestTree("child", "fail",
+		BTNodeCfg{Id: "fail", Name: "FailAction", Title: "Fail", Category: b3.ACTION},
+	))()
+	var tree = createTestTree("main", "seq",
+		BTNodeCfg{Id: "seq", Name: "Sequence", Category: b3.COMPOSITE, Children: []string{"ok", "job"}},
+		BTNodeCfg{Id: "ok", Name: "Succeeder", Category: b3.ACTION},
+		subTreeNode("job", "child", nil),
+	)
+
+	status, err := tree.TickE(&callLog{}, NewBlackboard())
+	if status != b3.ERROR || !errors.Is(err, errBoom) {
+		t.Fatal("status", status, "error", err)
+	}
+	var nodeErr *NodeError
+	if !errors.As(err, &nodeErr) {
+		t.Fatal("not a NodeError:", err)
+	}
+	if nodeErr.NodeID != "fail" || nodeErr.NodeName != "FailAction" || !reflect.DeepEqual(nodeErr.SubTreePath, []string{"job"}) {
+		t.Error("error:", nodeErr)
+	}
+	if nodeErr.Error() != "node fail(FailAction) in subtree job: boom" {
+		t.Error("message:", nodeErr.Error())
+	}
+}
+
+func TestTickEWithoutCause(t *testing.T) {
+	var tree = createTestTree("error", "seq",
+		BTNodeCfg{Id: "seq", Name: "Sequence", Category: b3.COMPOSITE, Children: []string{"err"}},
+		BTNodeCfg{Id: "err", Name: "Error", Category: b3.ACTION},
+	)
+	status, err := tree.TickE(nil, NewBlackboard())
+	var nodeErr *NodeError
+	if status != b3.ERROR || !errors.Is(err, ErrNodeError) || !errors.As(err, &nodeErr) || nodeErr.NodeID != "err" {
+		t.Error("status", status, "error", err)
+	}
+
+	var ok = createTestTree("ok", "ok", BTNodeCfg{Id: "ok", Name: "Succeeder", Category: b3.ACTION})
+	if status, err := ok.TickE(nil, NewBlackboard()); status != b3.SUCCESS || err != nil {
+		t.Error("status", status, "error", err)
+	}
+}
+
+func TestTickECancelled(t *testing.T) {
+	var tree = createRunTree()
+	var ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+	status, err := tree.TickWithE(nil, NewBlackboard(), TickOptions{Context: ctx})
+	if status != b3.ERROR || !errors.Is(err, context.Canceled) {
+		t.Error("status", status, "error", err)
+	}
+}
+
+//记录tick结束时的错误
+type errorObserver struct {
+	BaseTickObserver
+	err error
+}
+
+func (this *errorObserver) OnTickEnd(tick *Tick, status b3.Status) {
+	this.err = tick.GetError()
+}
+
+func TestTickEErrorHandled(t *testing.T) {
+	// the panic is turned into FAILURE after the child failed with an error
+	var tree = createTestTree("priority", "pri",
+		BTNodeCfg{Id: "pri", Name: "Priority", Category: b3.COMPOSITE, Children: []string{"panic", "ok"}},
+		BTNodeCfg{Id: "panic", Name: "PanicComposite", Category: b3.COMPOSITE, Children: []string{"fail"}},
+		BTNodeCfg{Id: "fail", Name: "FailAction", Category: b3.ACTION},
+		BTNodeCfg{Id: "ok", Name: "Succeeder", Category: b3.ACTION},
+	)
+	tree.SetPanicPolicy(PANIC_TO_FAILURE)
+	var observer = &errorObserver{}
+	tree.AddObserver(observer)
+
+	status, err := tree.TickE(&callLog{}, NewBlackboard())
+	if status != b3.SUCCESS || err != nil || observer.err != nil {
+		t.Error("status", status, "error", err, "tick error", observer.err)
+	}
+}
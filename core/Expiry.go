@@ -0,0 +1,271 @@
+package core
+
+import (
+	"sort"
+	"time"
+)
+
+//有过期key的内存所在的作用域
+type ttlScope struct {
+	treeScope string
+	nodeScope string
+}
+
+//写入带过期时间的值，返回旧值
+func (this *Memory) _swapTTL(key string, val interface{}, expires time.Time) interface{} {
+	if this._mutex != nil {
+		this._mutex.Lock()
+		defer this._mutex.Unlock()
+	}
+	var old = this._memory[key]
+	this._memory[key] = val
+	if this._expires == nil {
+		this._expires = make(map[string]time.Time)
+	}
+	this._expires[key] = expires
+	return old
+}
+
+//查找，过期的key被删除，expired为true时value是删除的值
+func (this *Memory) _lookup(key string, now func() time.Time) (value interface{}, ok bool, expired bool) {
+	if this._mutex != nil {
+		this._mutex.RLock()
+		value, ok = this._memory[key]
+		var expires, ttl = this._expires[key]
+		this._mutex.RUnlock()
+		if !ttl || now().Before(expires) {
+			return value, ok, false
+		}
+		this._mutex.Lock()
+		defer this._mutex.Unlock()
+	} else {
+		value, ok = this._memory[key]
+		if _, ttl := this._expires[key]; !ttl {
+			return value, ok, false
+		}
+	}
+
+	// check again, the key may have been written since
+	var expires, ttl = this._expires[key]
+	if !ttl || now().Before(expires) {
+		value, ok = this._memory[key]
+		return value, ok, false
+	}
+	value = this._memory[key]
+	delete(this._memory, key)
+	delete(this._expires, key)
+	return value, false, true
+}
+
+//删除所有过期的key，返回删除的值和剩下的过期key数量
+func (this *Memory) _purge(now time.Time) (map[string]interface{}, int) {
+	if this._mutex != nil {
+		this._mutex.Lock()
+		defer this._mutex.Unlock()
+	}
+	var expired map[string]interface{}
+	for key, expires := range this._expires {
+		if now.Before(expires) {
+			continue
+		}
+		if expired == nil {
+			expired = make(map[string]interface{})
+		}
+		expired[key] = this._memory[key]
+		delete(this._memory, key)
+		delete(this._expires, key)
+	}
+	return expired, len(this._expires)
+}
+
+//复制过期时间
+func (this *Memory) _copyExpires() map[string]time.Time {
+	if this._mutex != nil {
+		this._mutex.RLock()
+		defer this._mutex.RUnlock()
+	}
+	if len(this._expires) == 0 {
+		return nil
+	}
+	var expires = make(map[string]time.Time, len(this._expires))
+	for key, t := range this._expires {
+		expires[key] = t
+	}
+	return expires
+}
+
+/**
+ * Sets the clock of the idle timeout of the trees, and of the expiry times
+ * for the reads and writes made outside of a tick, nil restores the wall
+ * time (default). During a tick, the accessors of the tick (`tick.GetMem`,
+ * `tick.SetMemWithTTL`...) and the purge of the expired keys at its
+ * beginning use the clock of the ticking tree instead. Give the blackboard
+ * the same clock as the trees when they run on game time.
+**/
+func (this *Blackboard) SetClock(clock Clock) {
+	this._lock()
+	this._clock = clock
+	this._unlock()
+}
+
+/**
+ * The clock of the expiry times outside of the ticks, see `SetClock`.
+**/
+func (this *Blackboard) GetClock() Clock {
+	this._rlock()
+	defer this._runlock()
+	if this._clock != nil {
+		return this._clock
+	}
+	return WallClock{}
+}
+
+func (this *Blackboard) _now() time.Time {
+	return this.GetClock().Now()
+}
+
+//tick时清理空闲的树，按树的时钟清理过期的key
+func (this *Blackboard) _beginTick(tree *BehaviorTree, target interface{}) {
+	var now = this._now()
+	this._lock()
+	var idle = this._sweep(tree, now)
+	var ttl = len(this._ttlMemories) > 0
+	this._unlock()
+	// the running nodes of the idle trees are halted before dropping them
+	for _, old := range idle {
+		old.Abort(target, this)
+		this.ReleaseTree(old.id)
+	}
+	if ttl {
+		this._purgeExpired(tree.GetClock().Now())
+	}
+}
+
+/**
+ * Stores a value like `Set`, which expires after `ttl` on the clock of the
+ * blackboard (see `SetClock`), or of the tree with `tick.SetMemWithTTL`. Once expired, the key reads as missing and
+ * is removed, and the subscribers get a change with `Expired` set. The
+ * expired keys are removed when read, at the beginning of each tick and by
+ * `PurgeExpired`. Writing the key again with `Set` removes the expiry. A
+ * `ttl` of 0 or less stores the value without expiry.
+ *
+ *     blackboard.SetWithTTL("enemyPos", pos, 5*time.Second, "", "")
+ *
+ * @method SetWithTTL
+ * @param {String} key The key to be stored.
+ * @param {Object} value The value to be stored.
+ * @param {Duration} ttl The time to live.
+ * @param {String} treeScope The tree id if accessing the tree or node
+ *                           memory.
+ * @param {String} nodeScope The node id if accessing the node memory.
+**/
+func (this *Blackboard) SetWithTTL(key string, value interface{}, ttl time.Duration, treeScope, nodeScope string) {
+	this._setWithTTL(key, value, ttl, treeScope, nodeScope, this._now())
+}
+
+//同SetWithTTL，从now开始计时，返回严格模式拒绝写入的原因
+func (this *Blackboard) _setWithTTL(key string, value interface{}, ttl time.Duration, treeScope, nodeScope string, now time.Time) error {
+	if ttl <= 0 {
+		return this._setStrict(key, value, treeScope, nodeScope)
+	}
+	if err := this._checkStrict(key, value, treeScope, nodeScope); err != nil {
+		return err
+	}
+	if len(treeScope) == 0 {
+		nodeScope = ""
+	}
+	var memory = this._getMemory(treeScope, nodeScope)
+	var old = memory._swapTTL(key, value, now.Add(ttl))
+	this._trackTTL(memory, treeScope, nodeScope)
+	this._written(key, old, value, false, treeScope, nodeScope)
+	return nil
+}
+
+/**
+ * The time left before the key expires, false if the key is not set or
+ * has no expiry.
+**/
+func (this *Blackboard) GetTTL(key, treeScope, nodeScope string) (time.Duration, bool) {
+	if _, ok := this.Lookup(key, treeScope, nodeScope); !ok {
+		return 0, false
+	}
+	var memory = this._getMemory(treeScope, nodeScope)
+	if memory._mutex != nil {
+		memory._mutex.RLock()
+		defer memory._mutex.RUnlock()
+	}
+	var expires, ok = memory._expires[key]
+	if !ok {
+		return 0, false
+	}
+	return expires.Sub(this._now()), true
+}
+
+/**
+ * Removes all the expired keys now and notifies the subscribers. Returns
+ * the number of keys removed.
+**/
+func (this *Blackboard) PurgeExpired() int {
+	return this._purgeExpired(this._now())
+}
+
+func (this *Blackboard) _purgeExpired(now time.Time) int {
+	this._lock()
+	var memories = make(map[*Memory]ttlScope, len(this._ttlMemories))
+	for memory, scope := range this._ttlMemories {
+		memories[memory] = scope
+	}
+	this._unlock()
+
+	var changes []*BlackboardChange
+	for memory, scope := range memories {
+		var expired, left = memory._purge(now)
+		if left == 0 {
+			this._untrackTTL(memory)
+		}
+		for key, value := range expired {
+			changes = append(changes, &BlackboardChange{Key: key, TreeScope: scope.treeScope, NodeScope: scope.nodeScope, Old: value})
+		}
+	}
+
+	// notify in a stable order, for deterministic simulations
+	sort.Slice(changes, func(i, j int) bool {
+		var a, b = changes[i], changes[j]
+		if a.TreeScope != b.TreeScope {
+			return a.TreeScope < b.TreeScope
+		}
+		if a.NodeScope != b.NodeScope {
+			return a.NodeScope < b.NodeScope
+		}
+		return a.Key < b.Key
+	})
+	for _, change := range changes {
+		this._expired(change.Key, change.Old, change.TreeScope, change.NodeScope)
+	}
+	return len(changes)
+}
+
+func (this *Blackboard) _trackTTL(memory *Memory, treeScope, nodeScope string) {
+	this._lock()
+	defer this._unlock()
+	if this._ttlMemories == nil {
+		this._ttlMemories = make(map[*Memory]ttlScope)
+	}
+	this._ttlMemories[memory] = ttlScope{treeScope, nodeScope}
+}
+
+func (this *Blackboard) _untrackTTL(memory *Memory) {
+	this._lock()
+	defer this._unlock()
+	if len(memory._copyExpires()) == 0 {
+		delete(this._ttlMemories, memory)
+	}
+}
+
+//通知过期
+func (this *Blackboard) _expired(key string, value interface{}, treeScope, nodeScope string) {
+	if len(treeScope) == 0 {
+		nodeScope = ""
+	}
+	this._notify(&BlackboardChange{Key: key, TreeScope: treeScope, NodeScope: nodeScope, Old: value, Removed: true, Expired: true})
+}
@@ -0,0 +1,87 @@
+package core_test
+
+import (
+	"testing"
+	"time"
+
+	b3 "github.com/magicsea/behavior3go"
+	. "github.com/magicsea/behavior3go/config"
+	. "github.com/magicsea/behavior3go/core"
+)
+
+func TestSetWithTTL(t *testing.T) {
+	var clock = NewManualClock(time.Unix(0, 0))
+	var blackboard = NewBlackboard()
+	blackboard.SetClock(clock)
+
+	var expired []string
+	blackboard.SubscribeAll(func(change *BlackboardChange) {
+		if change.Expired {
+			expired = append(expired, change.Key)
+		}
+	})
+
+	blackboard.SetWithTTL("enemy", 7, time.Second, "", "")
+	blackboard.SetWithTTL("noise", 1, 3*time.Second, "tree", "node")
+	blackboard.SetWithTTL("kept", 1, time.Second, "", "")
+	blackboard.SetMem("kept", 2)
+
+	clock.Advance(500 * time.Millisecond)
+	if v := blackboard.GetInt("enemy", "", ""); v != 7 {
+		t.Error("enemy:", v)
+	}
+	if ttl, ok := blackboard.GetTTL("enemy", "", ""); !ok || ttl != 500*time.Millisecond {
+		t.Error("ttl:", ttl, ok)
+	}
+
+	clock.Advance(500 * time.Millisecond)
+	if v, ok := blackboard.Lookup("enemy", "", ""); ok {
+		t.Error("expired enemy:", v)
+	}
+	if len(expired) != 1 || expired[0] != "enemy" {
+		t.Error("expired:", expired)
+	}
+	if v := blackboard.GetInt("kept", "", ""); v != 2 {
+		t.Error("Set did not remove the expiry:", v)
+	}
+
+	clock.Advance(2 * time.Second)
+	if n := blackboard.PurgeExpired(); n != 1 {
+		t.Error("purged:", n)
+	}
+	if blackboard.Get("noise", "tree", "node") != nil {
+		t.Error("noise not expired")
+	}
+	if len(expired) != 2 {
+		t.Error("expired:", expired)
+	}
+}
+
+func TestTTLTreeClock(t *testing.T) {
+	var clock = NewManualClock(time.Unix(100, 0))
+	var tree = createTestTree("ttl", "set",
+		BTNodeCfg{Id: "set", Name: "SetKey", Category: b3.ACTION, Properties: map[string]interface{}{"key": "enemy", "ttl": 1000.0}},
+	)
+	tree.SetClock(clock)
+	var other = createCountTree()
+	other.SetClock(clock)
+	// the clock of the blackboard is only for the accesses outside of the ticks
+	var blackboard = NewBlackboard()
+	blackboard.SetClock(NewManualClock(time.Unix(0, 0)))
+
+	tree.Tick(nil, blackboard)
+	if ttl, ok := blackboard.GetTTL("enemy", "", ""); !ok || ttl != 101*time.Second {
+		t.Error("ttl:", ttl, ok)
+	}
+	var removed bool
+	blackboard.Subscribe("enemy", "", "", func(change *BlackboardChange) {
+		removed = change.Expired
+	})
+	clock.Advance(time.Second)
+	if status := other.Tick(&npc{}, blackboard); status != b3.RUNNING {
+		t.Error("status", status)
+	}
+	if !removed {
+		t.Error("not purged at the tick")
+	}
+}
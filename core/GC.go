@@ -0,0 +1,174 @@
+package core
+
+import (
+	"sort"
+	"time"
+)
+
+/**
+ * How a blackboard frees the memory it no longer needs, see
+ * `Blackboard.SetGCPolicy`.
+ *
+ * - **TreeIdleTimeout** The memory of a tree which was not ticked with the
+ *   blackboard for this time, on the clock of the blackboard, is dropped,
+ *   e.g. the old version of a hot-swapped tree. Its running nodes are
+ *   halted first, with the target of the tick which finds the tree idle.
+ *   0 keeps the memory of the trees forever.
+ * - **CompactNodeMemory** The memory of a node is dropped when the node
+ *   closes, instead of being kept until the next time it is opened. The
+ *   nodes which must remember something across executions, like
+ *   `Limiter`, implement `IMemoryRetainer`. The subscribers are not
+ *   notified of the dropped keys.
+**/
+type GCPolicy struct {
+	TreeIdleTimeout   time.Duration
+	CompactNodeMemory bool
+}
+
+/**
+ * Implemented by the nodes which keep values in their node memory across
+ * executions, so `GCPolicy.CompactNodeMemory` does not drop it.
+**/
+type IMemoryRetainer interface {
+	RetainMemory() bool
+}
+
+/**
+ * Sets how the blackboard frees its memory. The idle trees are dropped at
+ * the beginning of the ticks, at most once per `TreeIdleTimeout`.
+**/
+func (this *Blackboard) SetGCPolicy(policy GCPolicy) {
+	this._lock()
+	this._gc = policy
+	this._nextSweep = time.Time{}
+	this._unlock()
+}
+
+func (this *Blackboard) GetGCPolicy() GCPolicy {
+	this._rlock()
+	defer this._runlock()
+	return this._gc
+}
+
+/**
+ * Drops the memory of a tree: its per tree and per node memories and its
+ * open nodes. The running nodes are not halted, use `BehaviorTree.Abort`
+ * first if needed.
+ *
+ * @method ReleaseTree
+ * @param {String} treeScope The id of the tree.
+ * @return {Boolean} Whether the blackboard had memory for the tree.
+**/
+func (this *Blackboard) ReleaseTree(treeScope string) bool {
+	this._lock()
+	defer this._unlock()
+	return this._releaseTree(treeScope)
+}
+
+//必须持有锁
+func (this *Blackboard) _releaseTree(treeScope string) bool {
+	var treeMem, ok = this._treeMemory[treeScope]
+	if !ok {
+		return false
+	}
+	delete(this._treeMemory, treeScope)
+	delete(this._ttlMemories, treeMem.Memory)
+	for _, memory := range treeMem._nodeMemory {
+		delete(this._ttlMemories, memory)
+	}
+	return true
+}
+
+/**
+ * Drops the memory of a node of a tree.
+ *
+ * @method ReleaseNode
+ * @param {String} treeScope The id of the tree.
+ * @param {String} nodeScope The scope of the node, see
+ *                           `Tick.GetNodeScope`.
+ * @return {Boolean} Whether the blackboard had memory for the node.
+**/
+func (this *Blackboard) ReleaseNode(treeScope, nodeScope string) bool {
+	this._lock()
+	defer this._unlock()
+	var treeMem, ok = this._treeMemory[treeScope]
+	if !ok {
+		return false
+	}
+	var memory, found = treeMem._nodeMemory[nodeScope]
+	if found {
+		delete(treeMem._nodeMemory, nodeScope)
+		delete(this._ttlMemories, memory)
+	}
+	return found
+}
+
+//有内存的树ID，排序
+func (this *Blackboard) GetTreeScopes() []string {
+	this._rlock()
+	defer this._runlock()
+	var scopes = make([]string, 0, len(this._treeMemory))
+	for scope := range this._treeMemory {
+		scopes = append(scopes, scope)
+	}
+	sort.Strings(scopes)
+	return scopes
+}
+
+//树中有内存的节点作用域，排序
+func (this *Blackboard) GetNodeScopes(treeScope string) []string {
+	this._rlock()
+	defer this._runlock()
+	var treeMem, ok = this._treeMemory[treeScope]
+	if !ok {
+		return nil
+	}
+	var scopes = make([]string, 0, len(treeMem._nodeMemory))
+	for scope := range treeMem._nodeMemory {
+		scopes = append(scopes, scope)
+	}
+	sort.Strings(scopes)
+	return scopes
+}
+
+//tick开始时记录时间，清理空闲的树，返回还有运行中节点、要先打断的空闲树；必须持有锁
+func (this *Blackboard) _sweep(tree *BehaviorTree, now time.Time) []*BehaviorTree {
+	var treeMem, ok = this._treeMemory[tree.id]
+	if !ok {
+		treeMem = this._newTreeMemory()
+		this._treeMemory[tree.id] = treeMem
+	}
+	treeMem._lastTick = now
+
+	var timeout = this._gc.TreeIdleTimeout
+	if timeout <= 0 || now.Before(this._nextSweep) {
+		return nil
+	}
+	this._nextSweep = now.Add(timeout)
+	var deadline = now.Add(-timeout)
+	var running []*BehaviorTree
+	for scope, treeMem := range this._treeMemory {
+		// the scopes written outside of ticks are kept
+		if treeMem._lastTick.IsZero() || treeMem._lastTick.After(deadline) {
+			continue
+		}
+		var treeData = treeMem._treeData
+		if len(treeData.OpenNodes) == 0 && len(treeData.SuspendedNodes) == 0 {
+			this._releaseTree(scope)
+		} else if treeData.tree != nil {
+			running = append(running, treeData.tree)
+		}
+	}
+	return running
+}
+
+//节点关闭时释放节点内存
+func (this *Blackboard) _compactNode(treeScope, nodeScope string, node *BaseNode) {
+	if !this.GetGCPolicy().CompactNodeMemory {
+		return
+	}
+	if retainer, ok := node.IBaseWorker.(IMemoryRetainer); ok && retainer.RetainMemory() {
+		return
+	}
+	this.ReleaseNode(treeScope, nodeScope)
+}
@@ -0,0 +1,86 @@
+package core_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	. "github.com/magicsea/behavior3go/core"
+)
+
+func TestReleaseIdleTree(t *testing.T) {
+	var clock = NewManualClock(time.Unix(0, 0))
+	var old, swapped = createCountTree(), createCountTree()
+	old.SetClock(clock)
+	swapped.SetClock(clock)
+	var blackboard = NewBlackboard()
+	blackboard.SetClock(clock)
+	blackboard.SetGCPolicy(GCPolicy{TreeIdleTimeout: time.Minute})
+	blackboard.Set("note", 1, "manual", "")
+	var target = &npc{}
+
+	old.Tick(target, blackboard)
+	clock.Advance(time.Second)
+	// hot swap: the new version of the tree ticks with the same blackboard
+	swapped.Tick(target, blackboard)
+	if scopes := blackboard.GetTreeScopes(); len(scopes) != 3 {
+		t.Fatal("scopes:", scopes)
+	}
+
+	clock.Advance(time.Minute)
+	swapped.Tick(target, blackboard)
+	var scopes = blackboard.GetTreeScopes()
+	for _, scope := range scopes {
+		if scope == old.GetID() {
+			t.Error("idle tree kept")
+		}
+	}
+	if len(scopes) != 2 {
+		t.Error("scopes:", scopes)
+	}
+
+	if !blackboard.ReleaseTree(swapped.GetID()) || blackboard.ReleaseTree(swapped.GetID()) {
+		t.Error("ReleaseTree")
+	}
+}
+
+func TestReleaseIdleTreeHalts(t *testing.T) {
+	var clock = NewManualClock(time.Unix(0, 0))
+	var old, swapped = createRunTree(), createRunTree()
+	var blackboard = NewBlackboard()
+	blackboard.SetClock(clock)
+	blackboard.SetGCPolicy(GCPolicy{TreeIdleTimeout: time.Minute})
+	var log = &callLog{}
+
+	old.Tick(log, blackboard)
+	clock.Advance(2 * time.Minute)
+	*log = nil
+	swapped.Tick(log, blackboard)
+	// the running node of the old tree is halted before its memory is dropped
+	if expected := (callLog{"run:halt", "run:close", "run:open"}); !reflect.DeepEqual(*log, expected) {
+		t.Error("calls:", *log)
+	}
+	if scopes := blackboard.GetTreeScopes(); len(scopes) != 1 || scopes[0] != swapped.GetID() {
+		t.Error("scopes:", scopes)
+	}
+}
+
+func TestCompactNodeMemory(t *testing.T) {
+	var tree = createCountTree()
+	var blackboard = NewBlackboard()
+	var target = &npc{}
+
+	tree.Tick(target, blackboard)
+	if scopes := blackboard.GetNodeScopes(tree.GetID()); !reflect.DeepEqual(scopes, []string{"count", "ok", "seq"}) {
+		t.Error("scopes:", scopes)
+	}
+
+	blackboard.SetGCPolicy(GCPolicy{CompactNodeMemory: true})
+	blackboard.ReleaseNode(tree.GetID(), "ok")
+	tree.Tick(target, blackboard)
+	tree.Tick(target, blackboard)
+	// the succeeder closes at each tick, the running nodes keep their memory
+	if scopes := blackboard.GetNodeScopes(tree.GetID()); !reflect.DeepEqual(scopes, []string{"count", "seq"}) {
+		t.Error("scopes:", scopes)
+	}
+}
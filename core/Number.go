@@ -0,0 +1,198 @@
+package core
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"reflect"
+)
+
+var (
+	//值不是数字
+	ErrNotNumber = errors.New("value is not a number")
+	//数字转换会溢出或者丢失小数
+	ErrNumberRange = errors.New("number does not fit in the type")
+)
+
+/**
+ * The numeric types, for `ToNumber`.
+**/
+type Number interface {
+	~int | ~int8 | ~int16 | ~int32 | ~int64 |
+		~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64 | ~uintptr |
+		~float32 | ~float64
+}
+
+/**
+ * Retrieves a value of type T from the blackboard, see `Blackboard.Get`.
+ * Numbers are converted to T whatever their width, e.g. the float64 of a
+ * JSON config into an int, as long as the value fits: no overflow, no
+ * fractional part dropped. Returns false if the key is not set or the value
+ * can not be converted, never panics.
+ *
+ *     var hp, ok = b3.Get[int](blackboard, "hp", "", "")
+ *
+ * @method Get
+ * @param {Blackboard} blackboard The blackboard.
+ * @param {String} key The key to be retrieved.
+ * @param {String} treeScope The tree id if accessing the tree or node
+ *                           memory.
+ * @param {String} nodeScope The node id if accessing the node memory.
+ * @return {T} The value.
+ * @return {Boolean} Whether the value is set and has the type T.
+**/
+func Get[T any](blackboard *Blackboard, key, treeScope, nodeScope string) (T, bool) {
+	var zero T
+	var v = blackboard.Get(key, treeScope, nodeScope)
+	if v == nil {
+		return zero, false
+	}
+	if t, ok := v.(T); ok {
+		return t, true
+	}
+	var to = reflect.TypeOf(&zero).Elem()
+	if !isNumberKind(to.Kind()) {
+		return zero, false
+	}
+	var value, err = convertNumber(v, to)
+	if err != nil {
+		return zero, false
+	}
+	return value.Interface().(T), true
+}
+
+/**
+ * Same as `Get`, returns `def` if the key is not set or the value can not
+ * be converted to T.
+**/
+func GetOr[T any](blackboard *Blackboard, key, treeScope, nodeScope string, def T) T {
+	if t, ok := Get[T](blackboard, key, treeScope, nodeScope); ok {
+		return t
+	}
+	return def
+}
+
+/**
+ * Converts a number of any numeric type, or a `json.Number`, to T. Returns
+ * `ErrNotNumber` if v is not a number and `ErrNumberRange` if it does not
+ * fit in T.
+**/
+func ToNumber[T Number](v interface{}) (T, error) {
+	if t, ok := v.(T); ok {
+		return t, nil
+	}
+	var zero T
+	var value, err = convertNumber(v, reflect.TypeOf(zero))
+	if err != nil {
+		return zero, err
+	}
+	return value.Interface().(T), nil
+}
+
+//转成数字，和Go的类型转换一样不检查溢出和小数，不是数字时返回0，给Blackboard.GetInt等使用
+func castNumber[T Number](v interface{}) T {
+	if t, ok := v.(T); ok {
+		return t
+	}
+	var zero T
+	if n, ok := v.(json.Number); ok {
+		if i, err := n.Int64(); err == nil {
+			v = i
+		} else if f, err := n.Float64(); err == nil {
+			v = f
+		} else {
+			return zero
+		}
+	}
+	var from = reflect.ValueOf(v)
+	if v == nil || !isNumberKind(from.Kind()) {
+		return zero
+	}
+	return from.Convert(reflect.TypeOf(zero)).Interface().(T)
+}
+
+func isNumberKind(kind reflect.Kind) bool {
+	return isIntKind(kind) || isUintKind(kind) || kind == reflect.Float32 || kind == reflect.Float64
+}
+
+func isIntKind(kind reflect.Kind) bool {
+	return kind >= reflect.Int && kind <= reflect.Int64
+}
+
+func isUintKind(kind reflect.Kind) bool {
+	return kind >= reflect.Uint && kind <= reflect.Uintptr
+}
+
+//数字转成to类型，不能溢出，浮点转整数不能有小数
+func convertNumber(v interface{}, to reflect.Type) (reflect.Value, error) {
+	var result = reflect.New(to).Elem()
+	var from = reflect.ValueOf(v)
+	if n, ok := v.(json.Number); ok {
+		if i, err := n.Int64(); err == nil {
+			from = reflect.ValueOf(i)
+		} else if f, err := n.Float64(); err == nil {
+			from = reflect.ValueOf(f)
+		} else {
+			return result, ErrNotNumber
+		}
+	}
+	if v == nil || !isNumberKind(from.Kind()) || !isNumberKind(to.Kind()) {
+		return result, ErrNotNumber
+	}
+
+	switch {
+	case isIntKind(from.Kind()):
+		var i = from.Int()
+		switch {
+		case isIntKind(to.Kind()):
+			if result.OverflowInt(i) {
+				return result, ErrNumberRange
+			}
+			result.SetInt(i)
+		case isUintKind(to.Kind()):
+			if i < 0 || result.OverflowUint(uint64(i)) {
+				return result, ErrNumberRange
+			}
+			result.SetUint(uint64(i))
+		default:
+			result.SetFloat(float64(i))
+		}
+	case isUintKind(from.Kind()):
+		var u = from.Uint()
+		switch {
+		case isIntKind(to.Kind()):
+			if u > math.MaxInt64 || result.OverflowInt(int64(u)) {
+				return result, ErrNumberRange
+			}
+			result.SetInt(int64(u))
+		case isUintKind(to.Kind()):
+			if result.OverflowUint(u) {
+				return result, ErrNumberRange
+			}
+			result.SetUint(u)
+		default:
+			result.SetFloat(float64(u))
+		}
+	default:
+		var f = from.Float()
+		switch {
+		case isIntKind(to.Kind()):
+			// float64(math.MaxInt64) rounds up to 2^63, which does not fit
+			if f != math.Trunc(f) || f < math.MinInt64 || f >= math.MaxInt64 || result.OverflowInt(int64(f)) {
+				return result, ErrNumberRange
+			}
+			result.SetInt(int64(f))
+		case isUintKind(to.Kind()):
+			if f != math.Trunc(f) || f < 0 || f >= math.MaxUint64 || result.OverflowUint(uint64(f)) {
+				return result, ErrNumberRange
+			}
+			result.SetUint(uint64(f))
+		default:
+			if !math.IsInf(f, 0) && !math.IsNaN(f) && result.OverflowFloat(f) {
+				return result, ErrNumberRange
+			}
+			result.SetFloat(f)
+		}
+	}
+	return result, nil
+}
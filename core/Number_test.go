@@ -0,0 +1,125 @@
+package core_test
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"testing"
+
+	. "github.com/magicsea/behavior3go/core"
+)
+
+type level int
+
+func TestGetNumber(t *testing.T) {
+	var blackboard = NewBlackboard()
+	blackboard.SetMem("float", float64(3))
+	blackboard.SetMem("half", 2.5)
+	blackboard.SetMem("int8", int8(-7))
+	blackboard.SetMem("uint64", uint64(math.MaxUint64))
+	blackboard.SetMem("json", json.Number("42"))
+	blackboard.SetMem("name", "npc")
+
+	if v, ok := Get[int](blackboard, "float", "", ""); !ok || v != 3 {
+		t.Error("float to int:", v, ok)
+	}
+	if v, ok := Get[level](blackboard, "float", "", ""); !ok || v != 3 {
+		t.Error("float to level:", v, ok)
+	}
+	if v, ok := Get[int](blackboard, "half", "", ""); ok {
+		t.Error("half to int:", v)
+	}
+	if v, ok := Get[int64](blackboard, "int8", "", ""); !ok || v != -7 {
+		t.Error("int8 to int64:", v, ok)
+	}
+	if v, ok := Get[uint32](blackboard, "int8", "", ""); ok {
+		t.Error("int8 to uint32:", v)
+	}
+	if v, ok := Get[int64](blackboard, "uint64", "", ""); ok {
+		t.Error("uint64 to int64:", v)
+	}
+	if v, ok := Get[float32](blackboard, "json", "", ""); !ok || v != 42 {
+		t.Error("json to float32:", v, ok)
+	}
+	if v, ok := Get[string](blackboard, "name", "", ""); !ok || v != "npc" {
+		t.Error("string:", v, ok)
+	}
+	if v, ok := Get[int](blackboard, "name", "", ""); ok {
+		t.Error("string to int:", v)
+	}
+	if v, ok := Get[int](blackboard, "missing", "", ""); ok || v != 0 {
+		t.Error("missing:", v, ok)
+	}
+	if v := GetOr(blackboard, "half", "", "", 10); v != 10 {
+		t.Error("default:", v)
+	}
+
+	// the typed getters convert too
+	if v := blackboard.GetInt("float", "", ""); v != 3 {
+		t.Error("GetInt:", v)
+	}
+	if v := blackboard.GetFloat64("int8", "", ""); v != -7 {
+		t.Error("GetFloat64:", v)
+	}
+}
+
+func TestToNumber(t *testing.T) {
+	if _, err := ToNumber[int8](300); !errors.Is(err, ErrNumberRange) {
+		t.Error("300 to int8:", err)
+	}
+	if _, err := ToNumber[int64](math.Inf(1)); !errors.Is(err, ErrNumberRange) {
+		t.Error("inf to int64:", err)
+	}
+	if _, err := ToNumber[int64](float64(math.MaxInt64)); !errors.Is(err, ErrNumberRange) {
+		t.Error("2^63 to int64:", err)
+	}
+	if _, err := ToNumber[int]("1"); !errors.Is(err, ErrNotNumber) {
+		t.Error("string to int:", err)
+	}
+	if v, err := ToNumber[uint16](uint64(65535)); err != nil || v != 65535 {
+		t.Error("uint64 to uint16:", v, err)
+	}
+}
+
+func TestReadNumber(t *testing.T) {
+	// plain conversions, as before the checked ones
+	if v := ReadNumberToInt64(3.0); v != 3 {
+		t.Error("ReadNumberToInt64:", v)
+	}
+	if v := ReadNumberToInt64(uint64(math.MaxUint64)); v != -1 {
+		t.Error("ReadNumberToInt64 uint64:", v)
+	}
+	if v := ReadNumberToUInt64(int64(-1)); v != math.MaxUint64 {
+		t.Error("ReadNumberToUInt64 int64:", v)
+	}
+	if v := ReadNumberToUInt64("1"); v != 0 {
+		t.Error("ReadNumberToUInt64 string:", v)
+	}
+
+	var blackboard = NewBlackboard()
+	blackboard.SetMem("uint64", uint64(math.MaxUint64))
+	blackboard.SetMem("half", 2.5)
+	blackboard.SetMem("name", "npc")
+	if v := blackboard.GetInt64("uint64", "", ""); v != -1 {
+		t.Error("GetInt64:", v)
+	}
+	if v := blackboard.GetInt64Safe("half", "", ""); v != 2 {
+		t.Error("GetInt64Safe:", v)
+	}
+	if v := blackboard.GetUInt64("name", "", ""); v != 0 {
+		t.Error("GetUInt64:", v)
+	}
+	if v := blackboard.GetUInt64Safe("name", "", ""); v != 0 {
+		t.Error("GetUInt64Safe:", v)
+	}
+	if v := blackboard.GetInt32("uint64", "", ""); v != -1 {
+		t.Error("GetInt32:", v)
+	}
+	if v := blackboard.GetBool("name", "", ""); v {
+		t.Error("GetBool string:", v)
+	}
+	blackboard.SetMem("ready", true)
+	if v := blackboard.GetBool("ready", "", ""); !v {
+		t.Error("GetBool:", v)
+	}
+}
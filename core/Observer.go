@@ -0,0 +1,103 @@
+package core
+
+import (
+	"time"
+
+	b3 "github.com/magicsea/behavior3go"
+)
+
+//节点生命周期事件类型
+type NodeEventType uint8
+
+const (
+	EVENT_ENTER NodeEventType = 1
+	EVENT_OPEN  NodeEventType = 2
+	EVENT_TICK  NodeEventType = 3
+	EVENT_CLOSE NodeEventType = 4
+	EVENT_HALT  NodeEventType = 5
+	EVENT_EXIT  NodeEventType = 6
+)
+
+func (this NodeEventType) String() string {
+	switch this {
+	case EVENT_ENTER:
+		return "enter"
+	case EVENT_OPEN:
+		return "open"
+	case EVENT_TICK:
+		return "tick"
+	case EVENT_CLOSE:
+		return "close"
+	case EVENT_HALT:
+		return "halt"
+	case EVENT_EXIT:
+		return "exit"
+	}
+	return "unknown"
+}
+
+/**
+ * A node lifecycle event, passed to the observers of the tick.
+ *
+ * `Status` is only set for `EVENT_TICK` and `EVENT_EXIT`. `Elapsed` is the
+ * duration of `OnTick` for `EVENT_TICK` and the duration since
+ * `EVENT_ENTER` for `EVENT_EXIT`, measured on the wall clock. `Time` is
+ * read from the clock of the tree.
+ *
+ * The event is only valid during the callback, do not keep it.
+ *
+ * @module b3
+ * @class NodeEvent
+**/
+type NodeEvent struct {
+	Type  NodeEventType
+	Tick  *Tick
+	Node  IBaseNode
+	ID    string
+	Name  string
+	Title string
+	//节点内存的作用域，见Tick.GetNodeScope
+	Scope string
+
+	Status  b3.Status
+	Elapsed time.Duration
+	Time    time.Time
+
+	//执行时的子树栈，主树上为空
+	SubTrees []*SubTree
+}
+
+/**
+ * The TickObserver receives the lifecycle events of a tick: the begin and
+ * the end of the tick, and the enter/open/tick/close/halt/exit events of
+ * every node. Register it with `BehaviorTree.AddObserver` to see every
+ * tick of the tree, or pass it in `TickOptions` for a single tick.
+ *
+ * The tree is shared by many targets, so the observer may be called from
+ * several goroutines at once and must filter on `tick.Blackboard` or
+ * `tick.GetTarget()` to follow a single agent.
+ *
+ * @module b3
+ * @class TickObserver
+**/
+type TickObserver interface {
+	OnTickBegin(tick *Tick)
+	OnNodeEvent(event *NodeEvent)
+	OnTickEnd(tick *Tick, status b3.Status)
+}
+
+//空实现，自定义observer可以嵌入它只覆盖需要的方法
+type BaseTickObserver struct {
+}
+
+func (this *BaseTickObserver) OnTickBegin(tick *Tick) {
+
+}
+
+func (this *BaseTickObserver) OnNodeEvent(event *NodeEvent) {
+
+}
+
+func (this *BaseTickObserver) OnTickEnd(tick *Tick, status b3.Status) {
+
+}
@@ -0,0 +1,77 @@
+package core_test
+
+import (
+	"reflect"
+	"testing"
+
+	b3 "github.com/magicsea/behavior3go"
+	. "github.com/magicsea/behavior3go/config"
+	. "github.com/magicsea/behavior3go/core"
+)
+
+//按顺序记录事件
+type eventLog struct {
+	BaseTickObserver
+	events []string
+}
+
+func (this *eventLog) OnTickBegin(tick *Tick) {
+	this.events = append(this.events, "begin")
+}
+
+func (this *eventLog) OnNodeEvent(event *NodeEvent) {
+	this.events = append(this.events, event.ID+":"+event.Type.String())
+}
+
+func (this *eventLog) OnTickEnd(tick *Tick, status b3.Status) {
+	this.events = append(this.events, "end")
+}
+
+func TestObserverEventOrder(t *testing.T) {
+	var tree = createTestTree("observed", "seq",
+		BTNodeCfg{Id: "seq", Name: "Sequence", Category: b3.COMPOSITE, Children: []string{"ok"}},
+		BTNodeCfg{Id: "ok", Name: "Succeeder", Category: b3.ACTION},
+	)
+	var observer = &eventLog{}
+	tree.AddObserver(observer)
+	var blackboard = NewBlackboard()
+
+	tree.Tick(nil, blackboard)
+	var expected = []string{
+		"begin",
+		"seq:enter", "seq:open",
+		"ok:enter", "ok:open", "ok:tick", "ok:close", "ok:exit",
+		"seq:tick", "seq:close", "seq:exit",
+		"end",
+	}
+	if !reflect.DeepEqual(observer.events, expected) {
+		t.Error("events:", observer.events)
+	}
+
+	// an observer of a single tick sees only that tick
+	var once = &eventLog{}
+	tree.TickWith(nil, blackboard, TickOptions{Observers: []TickObserver{once}})
+	if !reflect.DeepEqual(once.events, expected) {
+		t.Error("tick events:", once.events)
+	}
+	tree.RemoveObserver(observer)
+	tree.Tick(nil, blackboard)
+	if len(observer.events) != 2*len(expected) || len(once.events) != len(expected) {
+		t.Error("events after removal:", len(observer.events), len(once.events))
+	}
+}
+
+func TestObserverHaltEvent(t *testing.T) {
+	var tree = createRunTree()
+	var observer = &eventLog{}
+	tree.AddObserver(observer)
+	var blackboard = NewBlackboard()
+
+	tree.Tick(nil, blackboard)
+	observer.events = nil
+	tree.Abort(nil, blackboard)
+	var expected = []string{"run:halt", "run:close", "seq:halt", "seq:close"}
+	if !reflect.DeepEqual(observer.events, expected) {
+		t.Error("events:", observer.events)
+	}
+}
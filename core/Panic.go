@@ -0,0 +1,101 @@
+package core
+
+import (
+	"fmt"
+	"runtime/debug"
+
+	b3 "github.com/magicsea/behavior3go"
+)
+
+//节点panic时的处理策略
+type PanicPolicy uint8
+
+const (
+	//不处理，panic继续向上抛出（默认）
+	PANIC_PROPAGATE PanicPolicy = 0
+	//节点返回FAILURE
+	PANIC_TO_FAILURE PanicPolicy = 1
+	//节点返回ERROR，tick带上PanicError
+	PANIC_TO_ERROR PanicPolicy = 2
+	//由PanicHandler决定节点的返回值
+	PANIC_HANDLER PanicPolicy = 3
+)
+
+/**
+ * Decides the status of a node which panicked, see
+ * `BehaviorTree.SetPanicHandler`.
+**/
+type PanicHandler func(tick *Tick, node IBaseNode, err *PanicError) b3.Status
+
+/**
+ * A panic recovered in a node, with the stack of the goroutine at the
+ * time of the panic.
+ *
+ * @module b3
+ * @class PanicError
+**/
+type PanicError struct {
+	Value interface{}
+	Stack []byte
+}
+
+func (this *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v", this.Value)
+}
+
+//进入节点时tick的各个栈深度，panic后恢复到这里
+type tickDepth struct {
+	openNodes int
+	subTrees  int
+	frames    int
+}
+
+func (this *Tick) _depth() tickDepth {
+	return tickDepth{len(this._openNodes), len(this._openSubtreeNodes), len(this._frames)}
+}
+
+func (this *Tick) _panicPolicy() PanicPolicy {
+	return this._panic
+}
+
+/**
+ * Turns a panic of `node` into a status, following the policy of the
+ * tree. The stacks of the tick are restored to what they were when the
+ * node was entered, then the node and the nodes it opened in this tick are
+ * halted from leaf to root.
+**/
+func (this *Tick) _recovered(node *BaseNode, depth tickDepth, value interface{}) b3.Status {
+	var err = &PanicError{Value: value, Stack: debug.Stack()}
+	// the panic is the error of the node, not its rejected writes
+	this._rejected = nil
+
+	if len(this._openSubtreeNodes) > depth.subTrees {
+		this._setSubTrees(this._openSubtreeNodes[:depth.subTrees])
+	}
+	if len(this._openNodes) > depth.openNodes {
+		var opened = append([]IBaseNode{}, this._openNodes[depth.openNodes:]...)
+		this._openNodes = this._openNodes[:depth.openNodes]
+		haltNodes(this, opened)
+	}
+
+	var status b3.Status
+	switch this._panic {
+	case PANIC_TO_FAILURE:
+		status = b3.FAILURE
+	case PANIC_HANDLER:
+		status = this._panicHandler(this, node, err)
+	default:
+		status = this.Fail(node, err)
+	}
+	if status != b3.ERROR {
+		this._error = nil
+	}
+
+	// the node was entered, let the observers see it exit
+	if len(this._frames) > depth.frames {
+		this._frames = this._frames[:depth.frames+1]
+		this._tickNode(node, status, 0)
+		this._exitNode(node)
+	}
+	return status
+}
@@ -0,0 +1,132 @@
+package core_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	b3 "github.com/magicsea/behavior3go"
+	. "github.com/magicsea/behavior3go/config"
+	. "github.com/magicsea/behavior3go/core"
+)
+
+//panic节点下面有一个运行中的子节点
+func createPanicTree(policy PanicPolicy) *BehaviorTree {
+	var tree = createTestTree("panic", "seq",
+		BTNodeCfg{Id: "seq", Name: "Sequence", Category: b3.COMPOSITE, Children: []string{"ok", "boom"}},
+		BTNodeCfg{Id: "ok", Name: "Succeeder", Category: b3.ACTION},
+		BTNodeCfg{Id: "boom", Name: "PanicComposite", Category: b3.COMPOSITE, Children: []string{"run"}},
+		BTNodeCfg{Id: "run", Name: "RunAction", Category: b3.ACTION},
+	)
+	tree.SetPanicPolicy(policy)
+	return tree
+}
+
+//panic节点打开的子节点被打断，下次tick重新打开
+func checkPanicHalt(t *testing.T, tree *BehaviorTree, log *callLog, blackboard *Blackboard) {
+	if expected := (callLog{"run:open", "run:halt", "run:close"}); !reflect.DeepEqual(*log, expected) {
+		t.Error("calls:", *log)
+	}
+	for _, id := range []string{"seq", "boom", "run"} {
+		if blackboard.GetBool("isOpen", tree.GetID(), id) {
+			t.Error(id, "still open")
+		}
+	}
+	*log = nil
+	tree.TickE(log, blackboard)
+	if len(*log) == 0 || (*log)[0] != "run:open" {
+		t.Error("calls:", *log)
+	}
+}
+
+func TestPanicPropagate(t *testing.T) {
+	var tree = createPanicTree(PANIC_PROPAGATE)
+	defer func() {
+		if r := recover(); r != "boom" {
+			t.Error("recovered:", r)
+		}
+	}()
+	tree.Tick(&callLog{}, NewBlackboard())
+	t.Error("panic not propagated")
+}
+
+func TestPanicToFailure(t *testing.T) {
+	var tree = createPanicTree(PANIC_TO_FAILURE)
+	var log = &callLog{}
+	var blackboard = NewBlackboard()
+
+	status, err := tree.TickE(log, blackboard)
+	if status != b3.FAILURE || err != nil {
+		t.Error("status", status, "error", err)
+	}
+	checkPanicHalt(t, tree, log, blackboard)
+}
+
+func TestPanicToError(t *testing.T) {
+	var tree = createPanicTree(PANIC_TO_ERROR)
+	var log = &callLog{}
+	var blackboard = NewBlackboard()
+
+	status, err := tree.TickE(log, blackboard)
+	var panicErr *PanicError
+	var nodeErr *NodeError
+	if status != b3.ERROR || !errors.As(err, &panicErr) || !errors.As(err, &nodeErr) {
+		t.Fatal("status", status, "error", err)
+	}
+	if panicErr.Value != "boom" || len(panicErr.Stack) == 0 || nodeErr.NodeID != "boom" {
+		t.Error("error:", err)
+	}
+	checkPanicHalt(t, tree, log, blackboard)
+}
+
+func TestPanicHandler(t *testing.T) {
+	var tree = createPanicTree(PANIC_PROPAGATE)
+	var handled []string
+	tree.SetPanicHandler(func(tick *Tick, node IBaseNode, err *PanicError) b3.Status {
+		handled = append(handled, node.GetID()+":"+err.Error())
+		return b3.SUCCESS
+	})
+	if tree.GetPanicPolicy() != PANIC_HANDLER {
+		t.Error("policy", tree.GetPanicPolicy())
+	}
+	var log = &callLog{}
+	var blackboard = NewBlackboard()
+
+	if status := tree.Tick(log, blackboard); status != b3.SUCCESS {
+		t.Error("status", status)
+	}
+	if !reflect.DeepEqual(handled, []string{"boom:panic: boom"}) {
+		t.Error("handled:", handled)
+	}
+	checkPanicHalt(t, tree, log, blackboard)
+
+	tree.SetPanicHandler(nil)
+	if tree.GetPanicPolicy() != PANIC_PROPAGATE {
+		t.Error("policy", tree.GetPanicPolicy())
+	}
+	defer func() {
+		if recover() == nil {
+			t.Error("PANIC_HANDLER accepted without handler")
+		}
+	}()
+	tree.SetPanicPolicy(PANIC_HANDLER)
+}
+
+//tick中修改策略，正在运行的tick使用开始时的策略
+func TestPanicPolicyWhileTicking(t *testing.T) {
+	var tree = createPanicTree(PANIC_TO_FAILURE)
+	var done = make(chan bool)
+	go func() {
+		for i := 0; i < 100; i++ {
+			if status := tree.Tick(&callLog{}, NewBlackboard()); status != b3.FAILURE && status != b3.ERROR {
+				t.Error("status", status)
+			}
+		}
+		done <- true
+	}()
+	for i := 0; i < 100; i++ {
+		tree.SetPanicPolicy(PANIC_TO_ERROR)
+		tree.SetPanicPolicy(PANIC_TO_FAILURE)
+	}
+	<-done
+}
@@ -0,0 +1,193 @@
+package core
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+/**
+ * The ports of a SubTree node, parsed from its properties. Each property
+ * binds a key of the global memory, as read and written by the nodes of the
+ * subtree, to a key of the caller or to a constant:
+ *
+ *     "target": "@enemyId"       reads and writes of target use enemyId
+ *     "result": "@attackResult"  same for result
+ *     "range":  3                target reads 3
+ *
+ * So one subtree, e.g. "AttackTarget", is reused with different keys. The
+ * keys without port keep their name, they are translated by the ports of
+ * the enclosing SubTree nodes if any. A constant port is read only, the
+ * writes to it are ignored. The per tree and per node memories are not
+ * translated.
+ *
+ * The ports belong to the tick: the nodes read and write through
+ * `tick.GetMem`, `tick.LookupMem`, `tick.SetMem` and `tick.RemoveMem`. The
+ * blackboard itself never translates, so the other trees and the game
+ * code sharing it see the keys as they are.
+ *
+ * @module b3
+ * @class SubTreePorts
+**/
+type SubTreePorts struct {
+	remap  map[string]string
+	values map[string]interface{}
+}
+
+/**
+ * Parses the ports from the properties of a SubTree node, nil if there is
+ * none. The declarations of blackboard keys (`bb.` prefix, see `Schema`)
+ * are skipped.
+**/
+func ParseSubTreePorts(properties map[string]interface{}) (*SubTreePorts, error) {
+	var ports = &SubTreePorts{}
+	for name, value := range properties {
+		if len(name) == 0 || strings.HasPrefix(name, SCHEMA_PREFIX) {
+			continue
+		}
+		if s, ok := value.(string); ok && strings.HasPrefix(s, "@") {
+			if len(s) == 1 {
+				return nil, fmt.Errorf("subtree port %s: empty key", name)
+			}
+			if ports.remap == nil {
+				ports.remap = make(map[string]string)
+			}
+			ports.remap[name] = s[1:]
+			continue
+		}
+		if ports.values == nil {
+			ports.values = make(map[string]interface{})
+		}
+		ports.values[name] = value
+	}
+	if ports.IsEmpty() {
+		return nil, nil
+	}
+	return ports, nil
+}
+
+func (this *SubTreePorts) IsEmpty() bool {
+	return this == nil || len(this.remap)+len(this.values) == 0
+}
+
+//端口名，排序
+func (this *SubTreePorts) Names() []string {
+	if this == nil {
+		return nil
+	}
+	var names = make([]string, 0, len(this.remap)+len(this.values))
+	for name := range this.remap {
+		names = append(names, name)
+	}
+	for name := range this.values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+/**
+ * The caller key bound to the port, or its constant value.
+ *
+ * @method Get
+ * @param {String} name The key used in the subtree.
+ * @return {String} The caller key, empty for a constant.
+ * @return {Object} The constant value.
+ * @return {Boolean} Whether the port exists.
+**/
+func (this *SubTreePorts) Get(name string) (string, interface{}, bool) {
+	if this == nil {
+		return "", nil, false
+	}
+	if key, ok := this.remap[name]; ok {
+		return key, nil, true
+	}
+	if value, ok := this.values[name]; ok {
+		return "", value, true
+	}
+	return "", nil, false
+}
+
+/**
+ * Translates a key of the global memory through the ports of the open
+ * SubTree nodes of the tick, from the innermost. Returns the key of the
+ * major tree, or the value of a constant port and true.
+ *
+ * @method PortKey
+ * @param {String} key The key used in the current subtree.
+ * @return {String} The key in the blackboard.
+ * @return {Object} The constant value.
+ * @return {Boolean} Whether the key is a constant port.
+**/
+func (this *Tick) PortKey(key string) (string, interface{}, bool) {
+	for i := len(this._openSubtreeNodes) - 1; i >= 0; i-- {
+		var ports = this._openSubtreeNodes[i].ports
+		if ports == nil {
+			continue
+		}
+		if mapped, ok := ports.remap[key]; ok {
+			key = mapped
+		} else if value, ok := ports.values[key]; ok {
+			return key, value, true
+		}
+	}
+	return key, nil, false
+}
+
+/**
+ * Retrieves a value of the global memory like `Blackboard.Lookup`, the key
+ * translated by the ports of the open SubTree nodes. The expiry times are
+ * checked on the clock of the tree.
+ *
+ * @method LookupMem
+ * @param {String} key The key used in the current subtree.
+ * @return {Object} The value stored.
+ * @return {Boolean} Whether the key is set.
+**/
+func (this *Tick) LookupMem(key string) (interface{}, bool) {
+	var mapped, value, constant = this.PortKey(key)
+	if constant {
+		return value, true
+	}
+	return this.Blackboard._lookupAt(mapped, "", "", this.clock.Now)
+}
+
+//同LookupMem，未设置时为nil
+func (this *Tick) GetMem(key string) interface{} {
+	var value, _ = this.LookupMem(key)
+	return value
+}
+
+/**
+ * Stores a value in the global memory like `Blackboard.SetMem`, the key
+ * translated by the ports of the open SubTree nodes. The writes to a
+ * constant port are ignored. If the strict schema of the blackboard
+ * rejects the value, the node fails with the error, see `SetSchema`.
+ *
+ * @method SetMem
+ * @param {String} key The key used in the current subtree.
+ * @param {Object} value The value to be stored.
+**/
+func (this *Tick) SetMem(key string, value interface{}) {
+	if mapped, _, constant := this.PortKey(key); !constant {
+		this._reject(this.Blackboard._setStrict(mapped, value, "", ""))
+	}
+}
+
+/**
+ * Stores a value like `SetMem`, which expires after `ttl` on the clock of
+ * the tree, see `Blackboard.SetWithTTL`.
+**/
+func (this *Tick) SetMemWithTTL(key string, value interface{}, ttl time.Duration) {
+	if mapped, _, constant := this.PortKey(key); !constant {
+		this._reject(this.Blackboard._setWithTTL(mapped, value, ttl, "", "", this.clock.Now()))
+	}
+}
+
+//同SetMem，删除key
+func (this *Tick) RemoveMem(key string) {
+	if mapped, _, constant := this.PortKey(key); !constant {
+		this.Blackboard.Remove(mapped)
+	}
+}
@@ -0,0 +1,117 @@
+package core_test
+
+import (
+	"testing"
+
+	b3 "github.com/magicsea/behavior3go"
+	. "github.com/magicsea/behavior3go/config"
+	. "github.com/magicsea/behavior3go/core"
+)
+
+//两个SubTree节点通过端口引用同一个子树，返回恢复子树加载的函数
+func createPortTree() (*BehaviorTree, func()) {
+	var restore = useSubTrees(createTestTree("attack", "copy",
+		BTNodeCfg{Id: "copy", Name: "CopyTarget", Category: b3.ACTION},
+	))
+	return createTestTree("main", "seq",
+		BTNodeCfg{Id: "seq", Name: "Sequence", Category: b3.COMPOSITE, Children: []string{"enemy", "fixed"}},
+		subTreeNode("enemy", "attack", map[string]interface{}{"target": "@enemyId", "result": "@attackResult"}),
+		subTreeNode("fixed", "attack", map[string]interface{}{"target": 5.0, "result": "@fixedResult"}),
+	), restore
+}
+
+func TestSubTreePorts(t *testing.T) {
+	tree, restore := createPortTree()
+	defer restore()
+
+	var blackboard = NewBlackboard()
+	blackboard.SetMem("enemyId", 42)
+	if status := tree.Tick(&npc{}, blackboard); status != b3.SUCCESS {
+		t.Fatal("status", status)
+	}
+	if v := blackboard.GetInt("attackResult", "", ""); v != 42 {
+		t.Error("attackResult:", v)
+	}
+	if v := blackboard.GetInt("fixedResult", "", ""); v != 5 {
+		t.Error("fixedResult:", v)
+	}
+	// the subtree wrote nil in target: enemyId was cleared, the constant was not
+	if blackboard.Get("enemyId", "", "") != nil {
+		t.Error("enemyId not written through the port")
+	}
+	for _, key := range []string{"target", "result"} {
+		if _, ok := blackboard.Lookup(key, "", ""); ok {
+			t.Error("port key leaked:", key)
+		}
+	}
+
+	if _, err := ParseSubTreePorts(map[string]interface{}{"target": "@"}); err == nil {
+		t.Error("empty port key accepted")
+	}
+}
+
+//子树中的节点调用target：写入共享黑板并tick另一个树
+func TestSubTreePortsSharedBlackboard(t *testing.T) {
+	defer useSubTrees(createTestTree("attack", "seq",
+		BTNodeCfg{Id: "seq", Name: "Sequence", Category: b3.COMPOSITE, Children: []string{"copy", "call"}},
+		BTNodeCfg{Id: "copy", Name: "CopyTarget", Category: b3.ACTION},
+		BTNodeCfg{Id: "call", Name: "CallAction", Category: b3.ACTION},
+	))()
+	var tree = createTestTree("main", "enemy",
+		subTreeNode("enemy", "attack", map[string]interface{}{"target": "@enemyId", "result": "@attackResult"}),
+	)
+	var other = createTestTree("other", "set",
+		BTNodeCfg{Id: "set", Name: "SetKey", Category: b3.ACTION, Properties: map[string]interface{}{"key": "result"}},
+	)
+
+	var blackboard = NewSyncBlackboard()
+	blackboard.SetMem("enemyId", 42)
+	var call = func() {
+		// the ports of the running subtree do not apply outside its tick
+		blackboard.SetMem("target", 7)
+		if v, _ := blackboard.Lookup("enemyId", "", ""); v != nil {
+			t.Error("enemyId:", v)
+		}
+		other.Tick(nil, blackboard)
+	}
+	if status := tree.Tick(call, blackboard); status != b3.SUCCESS {
+		t.Fatal("status", status)
+	}
+	if v := blackboard.GetInt("target", "", ""); v != 7 {
+		t.Error("target:", v)
+	}
+	if v := blackboard.GetInt("result", "", ""); v != 1 {
+		t.Error("result:", v)
+	}
+	if v := blackboard.GetInt("attackResult", "", ""); v != 42 {
+		t.Error("attackResult:", v)
+	}
+}
+
+//子树中的普通节点通过tick读写，也按端口转换
+func TestSubTreePortsNodes(t *testing.T) {
+	defer useSubTrees(createTestTree("mark", "seq",
+		BTNodeCfg{Id: "seq", Name: "Sequence", Category: b3.COMPOSITE, Children: []string{"set", "count"}},
+		BTNodeCfg{Id: "set", Name: "SetKey", Category: b3.ACTION, Properties: map[string]interface{}{"key": "flag"}},
+		BTNodeCfg{Id: "count", Name: "CountAction", Category: b3.ACTION},
+	))()
+	var tree = createTestTree("main", "job",
+		subTreeNode("job", "mark", map[string]interface{}{"flag": "@alarm", "ticks": "@jobTicks"}),
+	)
+
+	var blackboard = NewBlackboard()
+	if status := tree.Tick(&npc{}, blackboard); status != b3.RUNNING {
+		t.Fatal("status", status)
+	}
+	if v := blackboard.GetInt("alarm", "", ""); v != 1 {
+		t.Error("alarm:", v)
+	}
+	if v := blackboard.GetInt("jobTicks", "", ""); v != 1 {
+		t.Error("jobTicks:", v)
+	}
+	for _, key := range []string{"flag", "ticks"} {
+		if _, ok := blackboard.Lookup(key, "", ""); ok {
+			t.Error("port key leaked:", key)
+		}
+	}
+}
@@ -0,0 +1,207 @@
+package core
+
+import (
+	"sync"
+	"time"
+
+	b3 "github.com/magicsea/behavior3go"
+)
+
+/**
+ * The Waker is told by the nodes when the tree needs to be ticked again.
+ * It is set in `TickOptions` by event-driven drivers like
+ * `ReactiveAgent`, nodes reach it with `tick.WakeAt` and `tick.WakeFunc`.
+**/
+type Waker interface {
+	//尽快再次tick
+	Wake()
+	//在树的时钟到达t时再次tick
+	WakeAt(t time.Time)
+}
+
+/**
+ * Asks the driver to tick the tree again once the clock of the tree
+ * reaches `t`, e.g. when a timer of the node expires. Does nothing if the
+ * tree is ticked every frame.
+**/
+func (this *Tick) WakeAt(t time.Time) {
+	if this.waker != nil {
+		this.waker.WakeAt(t)
+	}
+}
+
+/**
+ * A function that asks the driver to tick the tree again as soon as
+ * possible. A `RUNNING` action can keep it and call it, from any goroutine,
+ * when its work completes.
+**/
+func (this *Tick) WakeFunc() func() {
+	if this.waker == nil {
+		return func() {}
+	}
+	return this.waker.Wake
+}
+
+/**
+ * ReactiveAgent ticks a tree for one target only when something relevant
+ * happened since the last tick:
+ *
+ * - a watched key of the base memory of the blackboard, or of a parent
+ *   blackboard when not set in this one, was written or removed since the
+ *   last tick started, by another goroutine or by the tree itself (watch
+ *   the keys the tree reads, not the ones it writes),
+ * - a running action called the function of `tick.WakeFunc()`,
+ * - a timer set with `tick.WakeAt` (e.g. by `Wait`) expired.
+ *
+ * Otherwise `Update` skips the tick. A running node which never wakes the
+ * agent is not ticked again until one of the events above happens.
+ *
+ *     var agent = b3.NewReactiveAgent(tree, npc, blackboard, "enemyId", "hp")
+ *     // every frame
+ *     agent.Update()
+ *
+ * @module b3
+ * @class ReactiveAgent
+**/
+type ReactiveAgent struct {
+	mutex      sync.Mutex
+	tree       *BehaviorTree
+	target     interface{}
+	blackboard *Blackboard
+	keys       map[string]bool
+	unwatch    func()
+
+	dirty  bool
+	wakeAt time.Time
+	status b3.Status
+}
+
+//ReactiveAgent作为Waker，tick期间收集定时
+type reactiveWaker struct {
+	agent  *ReactiveAgent
+	wakeAt time.Time
+}
+
+func (this *reactiveWaker) Wake() {
+	this.agent.Wake()
+}
+
+func (this *reactiveWaker) WakeAt(t time.Time) {
+	if this.wakeAt.IsZero() || t.Before(this.wakeAt) {
+		this.wakeAt = t
+	}
+}
+
+func NewReactiveAgent(tree *BehaviorTree, target interface{}, blackboard *Blackboard, keys ...string) *ReactiveAgent {
+	var agent = &ReactiveAgent{
+		tree:       tree,
+		target:     target,
+		blackboard: blackboard,
+		keys:       make(map[string]bool),
+		dirty:      true,
+	}
+	agent.Watch(keys...)
+	agent.unwatch = blackboard.watch(agent.onWrite)
+	return agent
+}
+
+//停止监听黑板
+func (this *ReactiveAgent) Close() {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	if this.unwatch != nil {
+		this.unwatch()
+		this.unwatch = nil
+	}
+}
+
+//监听基础内存的key
+func (this *ReactiveAgent) Watch(keys ...string) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	for _, key := range keys {
+		this.keys[key] = true
+	}
+}
+
+func (this *ReactiveAgent) Unwatch(keys ...string) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	for _, key := range keys {
+		delete(this.keys, key)
+	}
+}
+
+/**
+ * Marks the agent to be ticked at the next `Update`. Safe to call from
+ * any goroutine.
+**/
+func (this *ReactiveAgent) Wake() {
+	this.mutex.Lock()
+	this.dirty = true
+	this.mutex.Unlock()
+}
+
+/**
+ * Whether the next `Update` will tick the tree.
+**/
+func (this *ReactiveAgent) NeedsTick() bool {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	return this.needsTick()
+}
+
+//必须持有锁
+func (this *ReactiveAgent) needsTick() bool {
+	if this.dirty {
+		return true
+	}
+	return !this.wakeAt.IsZero() && !this.tree.GetClock().Now().Before(this.wakeAt)
+}
+
+/**
+ * Ticks the tree if needed. Returns the status of the tick, or the status
+ * of the last tick and false if skipped.
+**/
+func (this *ReactiveAgent) Update() (b3.Status, bool) {
+	this.mutex.Lock()
+	if !this.needsTick() {
+		var status = this.status
+		this.mutex.Unlock()
+		return status, false
+	}
+	this.mutex.Unlock()
+	return this.Tick(), true
+}
+
+/**
+ * Ticks the tree now, whether it is needed or not.
+**/
+func (this *ReactiveAgent) Tick() b3.Status {
+	var waker = &reactiveWaker{agent: this}
+
+	// writes from now on, even during the tick, need another tick
+	this.mutex.Lock()
+	this.dirty = false
+	this.mutex.Unlock()
+
+	var status = this.tree.TickWith(this.target, this.blackboard, TickOptions{Waker: waker})
+
+	this.mutex.Lock()
+	this.status = status
+	this.wakeAt = waker.wakeAt
+	this.mutex.Unlock()
+	return status
+}
+
+//基础内存的监听key被修改，tick期间的修改也要再tick，否则其他goroutine的写入会丢失
+func (this *ReactiveAgent) onWrite(change *BlackboardChange) {
+	if len(change.TreeScope) > 0 {
+		return
+	}
+	this.mutex.Lock()
+	if this.keys[change.Key] {
+		this.dirty = true
+	}
+	this.mutex.Unlock()
+}
@@ -0,0 +1,109 @@
+package core_test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	b3 "github.com/magicsea/behavior3go"
+	. "github.com/magicsea/behavior3go/config"
+	. "github.com/magicsea/behavior3go/core"
+)
+
+func TestReactiveAgentWatch(t *testing.T) {
+	var tree = createCountTree()
+	var target = &npc{}
+	var blackboard = NewBlackboard()
+	var agent = NewReactiveAgent(tree, target, blackboard, "enemy")
+	defer agent.Close()
+
+	if _, ticked := agent.Update(); !ticked {
+		t.Error("first update skipped")
+	}
+	if status, ticked := agent.Update(); ticked || status != b3.RUNNING {
+		t.Error("nothing changed, ticked", ticked, "status", status)
+	}
+	blackboard.SetMem("other", 1)
+	if _, ticked := agent.Update(); ticked {
+		t.Error("ticked on an unwatched key")
+	}
+	blackboard.SetMem("enemy", 1)
+	if _, ticked := agent.Update(); !ticked {
+		t.Error("watched key ignored")
+	}
+	agent.Wake()
+	if _, ticked := agent.Update(); !ticked {
+		t.Error("wake ignored")
+	}
+	if target.ticks != 3 {
+		t.Error("ticks:", target.ticks)
+	}
+}
+
+func TestReactiveAgentWatchParent(t *testing.T) {
+	var tree = createCountTree()
+	var world = NewSyncBlackboard()
+	var blackboard = NewBlackboard()
+	blackboard.SetParent(world)
+	var agent = NewReactiveAgent(tree, &npc{}, blackboard, "night")
+	defer agent.Close()
+
+	agent.Update()
+	world.SetMem("night", true)
+	if !agent.NeedsTick() {
+		t.Error("parent write ignored")
+	}
+	agent.Update()
+	blackboard.SetMem("night", false)
+	agent.Update()
+	world.SetMem("night", false)
+	if agent.NeedsTick() {
+		t.Error("woken by a shadowed parent key")
+	}
+}
+
+func TestReactiveAgentWakeAt(t *testing.T) {
+	var tree = createTestTree("wait", "wait",
+		BTNodeCfg{Id: "wait", Name: "Wait", Category: b3.ACTION, Properties: map[string]interface{}{"milliseconds": 1000.0}},
+	)
+	var clock = NewManualClock(time.Unix(100, 0))
+	tree.SetClock(clock)
+	var agent = NewReactiveAgent(tree, nil, NewBlackboard())
+	defer agent.Close()
+
+	if status, _ := agent.Update(); status != b3.RUNNING {
+		t.Fatal("status", status)
+	}
+	clock.Advance(time.Second)
+	if agent.NeedsTick() {
+		t.Error("woken before the timer")
+	}
+	clock.Advance(time.Millisecond)
+	if status, ticked := agent.Update(); !ticked || status != b3.SUCCESS {
+		t.Error("timer ignored, ticked", ticked, "status", status)
+	}
+}
+
+func TestReactiveAgentWriteDuringTick(t *testing.T) {
+	var tree = createTestTree("call", "call",
+		BTNodeCfg{Id: "call", Name: "CallAction", Category: b3.ACTION},
+	)
+	var blackboard = NewBlackboard()
+	// another goroutine writes the watched key while the tree runs
+	var write = func() {
+		var wg sync.WaitGroup
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			blackboard.SetMem("enemy", 1)
+		}()
+		wg.Wait()
+	}
+	var agent = NewReactiveAgent(tree, write, blackboard, "enemy")
+	defer agent.Close()
+
+	agent.Update()
+	if !agent.NeedsTick() {
+		t.Error("write during the tick lost")
+	}
+}
@@ -0,0 +1,383 @@
+package core
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/magicsea/behavior3go/config"
+)
+
+//黑板key的作用域
+const (
+	SCOPE_BASE = "base"
+	SCOPE_TREE = "tree"
+	SCOPE_NODE = "node"
+)
+
+//声明key的属性前缀
+const SCHEMA_PREFIX = "bb."
+
+/**
+ * A blackboard key declared in the properties of a tree or a project, see
+ * `ParseSchema`.
+**/
+type KeyDecl struct {
+	Key        string
+	Type       string
+	Default    interface{}
+	HasDefault bool
+	Scope      string
+}
+
+/**
+ * The blackboard keys declared in the properties of a tree or a project.
+ *
+ * A key is declared by a property named `bb.<key>`, whose value is
+ * `<type>[=<default>][@<scope>]`:
+ *
+ *     "bb.hp":       "int=100"
+ *     "bb.enemyId":  "string"
+ *     "bb.seen":     "bool=false@tree"
+ *     "bb.speed":    2.5
+ *
+ * The types are bool, string, int, int32, int64, uint64, float64 and any,
+ * the scopes base (default), tree and node. A number or bool value
+ * declares a key of its type with this default.
+ *
+ * When a tree declares keys, `BehaviorTree.Load` checks that the node
+ * properties refer to declared keys: the properties named `key` or ending
+ * with `Key`, and the string values starting with `@` (`"@hp"`). This is
+ * only guessed from the property names, the nodes do not declare the keys
+ * they use: a key read under another property name or built in code is not
+ * checked, and a property named like a key which is not one must be
+ * renamed or declared. With `Blackboard.SetSchema`, the blackboard reads
+ * the defaults and can reject the writes of the wrong type.
+ *
+ * @module b3
+ * @class Schema
+**/
+type Schema struct {
+	keys map[string]*KeyDecl
+}
+
+func NewSchema() *Schema {
+	return &Schema{keys: make(map[string]*KeyDecl)}
+}
+
+/**
+ * Parses the keys declared in properties, the other properties are
+ * ignored.
+**/
+func ParseSchema(properties map[string]interface{}) (*Schema, error) {
+	var schema = NewSchema()
+	for name, value := range properties {
+		if !strings.HasPrefix(name, SCHEMA_PREFIX) {
+			continue
+		}
+		var decl, err = parseKeyDecl(strings.TrimPrefix(name, SCHEMA_PREFIX), value)
+		if err != nil {
+			return nil, err
+		}
+		schema.keys[decl.Key] = decl
+	}
+	return schema, nil
+}
+
+func parseKeyDecl(key string, value interface{}) (*KeyDecl, error) {
+	if len(key) == 0 {
+		return nil, fmt.Errorf("schema: empty key name")
+	}
+	var decl = &KeyDecl{Key: key, Scope: SCOPE_BASE}
+	switch v := value.(type) {
+	case bool:
+		decl.Type, decl.Default, decl.HasDefault = "bool", v, true
+		return decl, nil
+	case float64:
+		if v == math.Trunc(v) && math.Abs(v) < 1<<53 {
+			decl.Type, decl.Default = "int", int(v)
+		} else {
+			decl.Type, decl.Default = "float64", v
+		}
+		decl.HasDefault = true
+		return decl, nil
+	case string:
+	default:
+		return nil, fmt.Errorf("schema: key %s: invalid declaration %v", key, value)
+	}
+
+	var spec = value.(string)
+	if i := strings.LastIndex(spec, "@"); i >= 0 {
+		switch scope := spec[i+1:]; scope {
+		case SCOPE_BASE, SCOPE_TREE, SCOPE_NODE:
+			decl.Scope = scope
+			spec = spec[:i]
+		default:
+			if !strings.Contains(spec, "=") {
+				return nil, fmt.Errorf("schema: key %s: invalid scope %s", key, scope)
+			}
+		}
+	}
+	var def string
+	if i := strings.Index(spec, "="); i >= 0 {
+		def = spec[i+1:]
+		decl.HasDefault = true
+		spec = spec[:i]
+	}
+	decl.Type = strings.TrimSpace(spec)
+	if _, ok := schemaTypes[decl.Type]; !ok {
+		return nil, fmt.Errorf("schema: key %s: invalid type %s", key, decl.Type)
+	}
+	if decl.HasDefault {
+		var err error
+		if decl.Default, err = parseDefault(decl.Type, def); err != nil {
+			return nil, fmt.Errorf("schema: key %s: invalid default %q: %v", key, def, err)
+		}
+	}
+	return decl, nil
+}
+
+//声明的类型，any为nil
+var schemaTypes = map[string]reflect.Type{
+	"bool":    reflect.TypeOf(false),
+	"string":  reflect.TypeOf(""),
+	"int":     reflect.TypeOf(int(0)),
+	"int32":   reflect.TypeOf(int32(0)),
+	"int64":   reflect.TypeOf(int64(0)),
+	"uint64":  reflect.TypeOf(uint64(0)),
+	"float64": reflect.TypeOf(float64(0)),
+	"any":     nil,
+}
+
+func parseDefault(typ, def string) (interface{}, error) {
+	switch typ {
+	case "bool":
+		return strconv.ParseBool(def)
+	case "string", "any":
+		return def, nil
+	case "float64":
+		return strconv.ParseFloat(def, 64)
+	case "uint64":
+		return strconv.ParseUint(def, 10, 64)
+	}
+	var i, err = strconv.ParseInt(def, 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	var value, cerr = convertNumber(i, schemaTypes[typ])
+	if cerr != nil {
+		return nil, cerr
+	}
+	return value.Interface(), nil
+}
+
+/**
+ * Adds the declarations of `other`, which replace the ones of the same
+ * keys. Returns the schema.
+**/
+func (this *Schema) Merge(other *Schema) *Schema {
+	if other != nil {
+		for key, decl := range other.keys {
+			this.keys[key] = decl
+		}
+	}
+	return this
+}
+
+//声明的key，nil时不存在
+func (this *Schema) Get(key string) *KeyDecl {
+	if this == nil {
+		return nil
+	}
+	return this.keys[key]
+}
+
+func (this *Schema) IsEmpty() bool {
+	return this == nil || len(this.keys) == 0
+}
+
+//所有声明的key，排序
+func (this *Schema) Keys() []string {
+	if this == nil {
+		return nil
+	}
+	var keys = make([]string, 0, len(this.keys))
+	for key := range this.keys {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+/**
+ * Checks that a value can be stored in the key: nil, a value of the
+ * declared type, or a number which converts to it without loss.
+**/
+func (this *KeyDecl) Check(value interface{}) error {
+	var t = schemaTypes[this.Type]
+	if value == nil || t == nil || reflect.TypeOf(value) == t {
+		return nil
+	}
+	if isNumberKind(t.Kind()) {
+		if _, err := convertNumber(value, t); err == nil {
+			return nil
+		}
+	}
+	return fmt.Errorf("blackboard key %s is %s, not %T", this.Key, this.Type, value)
+}
+
+//作用域是否匹配
+func (this *KeyDecl) inScope(treeScope, nodeScope string) bool {
+	switch this.Scope {
+	case SCOPE_TREE:
+		return len(treeScope) > 0 && len(nodeScope) == 0
+	case SCOPE_NODE:
+		return len(treeScope) > 0 && len(nodeScope) > 0
+	}
+	return len(treeScope) == 0
+}
+
+//节点属性中引用的key，按属性名和"@"前缀推断，不是节点的声明
+func referencedKeys(properties map[string]interface{}) map[string]string {
+	var refs = make(map[string]string)
+	for name, value := range properties {
+		var s, ok = value.(string)
+		if !ok || len(s) == 0 {
+			continue
+		}
+		if strings.HasPrefix(s, "@") && len(s) > 1 {
+			refs[name] = s[1:]
+		} else if name == "key" || strings.HasSuffix(name, "Key") {
+			refs[name] = s
+		}
+	}
+	return refs
+}
+
+//检查节点引用的key都已声明
+func (this *Schema) checkNodes(data *config.BTTreeCfg) error {
+	var ids = make([]string, 0, len(data.Nodes))
+	for id := range data.Nodes {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	var errs []string
+	for _, id := range ids {
+		var spec = data.Nodes[id]
+		for name, key := range referencedKeys(spec.Properties) {
+			if this.Get(key) == nil {
+				errs = append(errs, fmt.Sprintf("node %s(%s) property %s: undeclared key %s", id, spec.Title, name, key))
+			}
+		}
+	}
+	if len(errs) > 0 {
+		sort.Strings(errs)
+		return fmt.Errorf("tree %s: %s", data.Title, strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+/**
+ * Sets the schema of the blackboard, usually the one of the tree
+ * (`BehaviorTree.GetSchema`). The declared keys missing in the blackboard
+ * read as their default. In strict mode, a write of a declared key with a
+ * value of the wrong type, or of an undeclared key in the global memory, is
+ * rejected: `Set` does not write. The nodes writing with `tick.SetMem` fail
+ * with the error (`tick.Fail`, see `BehaviorTree.TickE`), use `TrySet` to
+ * get the error of the other writes.
+ *
+ * @method SetSchema
+ * @param {Schema} schema The declared keys, nil to remove the schema.
+ * @param {Boolean} strict Whether to reject the wrong writes.
+**/
+func (this *Blackboard) SetSchema(schema *Schema, strict bool) {
+	this._lock()
+	this._schema = schema
+	this._strict = strict
+	this._unlock()
+}
+
+func (this *Blackboard) GetSchema() *Schema {
+	this._rlock()
+	defer this._runlock()
+	return this._schema
+}
+
+/**
+ * Stores a value like `Set`, returns an error instead of writing if the
+ * schema of the blackboard rejects the value, strict mode or not.
+**/
+func (this *Blackboard) TrySet(key string, value interface{}, treeScope, nodeScope string) error {
+	var schema, strict = this._getSchema()
+	if err := checkWrite(schema, strict, key, value, treeScope, nodeScope); err != nil {
+		return err
+	}
+	this._set(key, value, treeScope, nodeScope)
+	return nil
+}
+
+func (this *Blackboard) _getSchema() (*Schema, bool) {
+	this._rlock()
+	defer this._runlock()
+	return this._schema, this._strict
+}
+
+//严格模式下检查写入，返回拒绝的原因
+func (this *Blackboard) _checkStrict(key string, value interface{}, treeScope, nodeScope string) error {
+	var schema, strict = this._getSchema()
+	if !strict {
+		return nil
+	}
+	return checkWrite(schema, strict, key, value, treeScope, nodeScope)
+}
+
+//tick中被拒绝的写入，由写入的节点返回ERROR，只保留第一个
+func (this *Tick) _reject(err error) {
+	if err != nil && this._rejected == nil {
+		this._rejected = err
+	}
+}
+
+//取出被拒绝的写入的错误
+func (this *Tick) _takeRejected() error {
+	var err = this._rejected
+	this._rejected = nil
+	return err
+}
+
+func checkWrite(schema *Schema, strict bool, key string, value interface{}, treeScope, nodeScope string) error {
+	if schema == nil {
+		return nil
+	}
+	if len(treeScope) == 0 {
+		nodeScope = ""
+	}
+	var decl = schema.Get(key)
+	if decl != nil && decl.inScope(treeScope, nodeScope) {
+		return decl.Check(value)
+	}
+	if strict && len(treeScope) == 0 {
+		return fmt.Errorf("blackboard key %s is not declared", key)
+	}
+	return nil
+}
+
+//声明的默认值
+func (this *Blackboard) _default(key, treeScope, nodeScope string) (interface{}, bool) {
+	var schema, _ = this._getSchema()
+	var decl = schema.Get(key)
+	if decl == nil || !decl.HasDefault {
+		return nil, false
+	}
+	if len(treeScope) == 0 {
+		nodeScope = ""
+	}
+	if !decl.inScope(treeScope, nodeScope) {
+		return nil, false
+	}
+	return decl.Default, true
+}
@@ -0,0 +1,139 @@
+package core_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	b3 "github.com/magicsea/behavior3go"
+	. "github.com/magicsea/behavior3go/config"
+	. "github.com/magicsea/behavior3go/core"
+	. "github.com/magicsea/behavior3go/loader"
+)
+
+func createSchemaTree(key string, schema *Schema) *BehaviorTree {
+	var config = treeConfig("schema", "set",
+		BTNodeCfg{Id: "set", Name: "SetKey", Title: "SetKey", Category: b3.ACTION, Properties: map[string]interface{}{"key": key}},
+	)
+	config.Properties = map[string]interface{}{
+		"bb.alarm": "int=0",
+	}
+	return CreateBevTreeFromConfigWithSchema(config, testStructMaps(), schema)
+}
+
+func TestSchemaLoad(t *testing.T) {
+	var tree = createSchemaTree("alarm", nil)
+	if tree.GetSchema().Get("alarm") == nil {
+		t.Fatal("alarm not declared")
+	}
+
+	project, err := ParseSchema(map[string]interface{}{"bb.enemyId": "string@base", "title": "project"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	createSchemaTree("enemyId", project)
+
+	defer func() {
+		var r = recover()
+		if r == nil || !strings.Contains(r.(string), "undeclared key alaram") {
+			t.Error("typo accepted:", r)
+		}
+	}()
+	createSchemaTree("alaram", project)
+}
+
+func TestParseSchema(t *testing.T) {
+	var schema, err = ParseSchema(map[string]interface{}{
+		"bb.hp":    "int=100",
+		"bb.seen":  "bool=true@tree",
+		"bb.mail":  "string=a@b",
+		"bb.speed": 2.5,
+		"bb.any":   "any@node",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var expected = map[string]KeyDecl{
+		"hp":    {Key: "hp", Type: "int", Default: 100, HasDefault: true, Scope: SCOPE_BASE},
+		"seen":  {Key: "seen", Type: "bool", Default: true, HasDefault: true, Scope: SCOPE_TREE},
+		"mail":  {Key: "mail", Type: "string", Default: "a@b", HasDefault: true, Scope: SCOPE_BASE},
+		"speed": {Key: "speed", Type: "float64", Default: 2.5, HasDefault: true, Scope: SCOPE_BASE},
+		"any":   {Key: "any", Type: "any", Scope: SCOPE_NODE},
+	}
+	for key, decl := range expected {
+		if got := schema.Get(key); got == nil || *got != decl {
+			t.Error(key, got)
+		}
+	}
+
+	for _, spec := range []interface{}{"integer", "int=abc", "int@world", "int8=1000", nil} {
+		if _, err := ParseSchema(map[string]interface{}{"bb.x": spec}); err == nil {
+			t.Error("accepted", spec)
+		}
+	}
+}
+
+func TestBlackboardSchema(t *testing.T) {
+	var schema, _ = ParseSchema(map[string]interface{}{
+		"bb.hp":   "int=100",
+		"bb.seen": "bool=false@tree",
+	})
+	var blackboard = NewBlackboard()
+	blackboard.SetSchema(schema, true)
+
+	if v := blackboard.GetInt("hp", "", ""); v != 100 {
+		t.Error("default hp:", v)
+	}
+	if v, ok := blackboard.Lookup("seen", "tree", ""); !ok || v != false {
+		t.Error("default seen:", v, ok)
+	}
+	blackboard.SetMem("hp", 3.0)
+	if err := blackboard.TrySet("hp", "full", "", ""); err == nil {
+		t.Error("string accepted in hp")
+	}
+	if err := blackboard.TrySet("hpp", 1, "", ""); err == nil {
+		t.Error("undeclared key accepted")
+	}
+	// the memory of the nodes is not checked
+	blackboard.Set("isOpen", true, "tree", "node")
+
+	// a wrong write is dropped, it does not panic
+	blackboard.Set("seen", 1, "tree", "")
+	if v, _ := blackboard.Lookup("seen", "tree", ""); v != false {
+		t.Error("strict mode accepted a wrong write:", v)
+	}
+}
+
+func TestSchemaStrictFailsNode(t *testing.T) {
+	project, err := ParseSchema(map[string]interface{}{"bb.enemyId": "string"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var tree = createSchemaTree("enemyId", project)
+	var blackboard = NewBlackboard()
+	blackboard.SetSchema(tree.GetSchema(), true)
+
+	// a write rejected outside of the ticks fails no node
+	blackboard.SetMem("enemyId", 1)
+	if status, err := createSchemaTree("alarm", project).TickE(nil, blackboard); status != b3.SUCCESS {
+		t.Error("status", status, "error", err)
+	}
+
+	status, err := tree.TickE(nil, blackboard)
+	var nodeErr *NodeError
+	if status != b3.ERROR || !errors.As(err, &nodeErr) || nodeErr.NodeID != "set" {
+		t.Fatal("status", status, "error", err)
+	}
+	if !strings.Contains(err.Error(), "enemyId is string") {
+		t.Error("error:", err)
+	}
+	if _, ok := blackboard.Lookup("enemyId", "", ""); ok {
+		t.Error("wrong write stored")
+	}
+
+	// not strict, the write goes through
+	blackboard.SetSchema(tree.GetSchema(), false)
+	if status, err := tree.TickE(nil, blackboard); status != b3.SUCCESS {
+		t.Error("status", status, "error", err)
+	}
+}
@@ -0,0 +1,115 @@
+package core
+
+import (
+	"fmt"
+	"strings"
+)
+
+//节点作用域中SubTree节点ID的分隔符
+const SCOPE_SEPARATOR = "/"
+
+/**
+ * The node scope of the memory of `node` in the blackboard: the ids of the
+ * SubTree nodes the traversal is in, then the id of the node, separated by
+ * `/`. On the major tree it is the id of the node. So a subtree used by two
+ * SubTree nodes keeps the memory of its nodes apart:
+ *
+ *     tick.Blackboard.Set("startTime", now, tick.GetTree().GetID(), tick.GetNodeScope(this))
+ *
+ * @method GetNodeScope
+ * @param {IBaseNode} node The node accessing its memory.
+ * @return {String} The node scope.
+**/
+func (this *Tick) GetNodeScope(node IBaseNode) string {
+	if len(this._scopePrefix) == 0 {
+		return node.GetID()
+	}
+	return this._scopePrefix + node.GetID()
+}
+
+//设置子树栈，更新节点作用域前缀
+func (this *Tick) _setSubTrees(subTrees []*SubTree) {
+	this._openSubtreeNodes = subTrees
+	if len(subTrees) == 0 {
+		this._scopePrefix = ""
+		return
+	}
+	this._scopePrefix = subTreePath(subTrees) + SCOPE_SEPARATOR
+}
+
+/**
+ * An open node of a subtree, with the SubTree nodes it was opened in. The
+ * open nodes of the major tree are recorded as their `*BaseNode`.
+**/
+type scopedNode struct {
+	*BaseNode
+	subTrees []*SubTree
+	scope    string
+}
+
+//记录打开的节点，子树中的节点记录子树栈
+func (this *Tick) _scopedNode(node *BaseNode) IBaseNode {
+	if len(this._openSubtreeNodes) == 0 {
+		return node
+	}
+	return &scopedNode{
+		BaseNode: node,
+		subTrees: append([]*SubTree{}, this._openSubtreeNodes...),
+		scope:    this.GetNodeScope(node),
+	}
+}
+
+//打开节点的作用域
+func nodeScope(node IBaseNode) string {
+	if scoped, ok := node.(*scopedNode); ok {
+		return scoped.scope
+	}
+	return node.GetID()
+}
+
+//打开节点所在的子树栈
+func nodeSubTrees(node IBaseNode) []*SubTree {
+	if scoped, ok := node.(*scopedNode); ok {
+		return scoped.subTrees
+	}
+	return nil
+}
+
+//在节点打开时的子树栈中打断节点，子树端口随子树栈还原
+func (this *Tick) _haltScoped(node IBaseNode) {
+	var saved = this._openSubtreeNodes
+	this._setSubTrees(nodeSubTrees(node))
+	defer this._setSubTrees(saved)
+	if this.Blackboard.GetBool("isOpen", this.tree.id, this.GetNodeScope(node)) {
+		node._halt(this)
+	}
+}
+
+func openNodeScopes(nodes []IBaseNode) []string {
+	var scopes = make([]string, 0, len(nodes))
+	for _, node := range nodes {
+		scopes = append(scopes, nodeScope(node))
+	}
+	return scopes
+}
+
+//节点作用域还原为树中的节点
+func resolveScope(tree *BehaviorTree, scope string) (IBaseNode, error) {
+	var ids = strings.Split(scope, SCOPE_SEPARATOR)
+	var subTrees = make([]*SubTree, 0, len(ids)-1)
+	for _, id := range ids[:len(ids)-1] {
+		var sub, ok = tree.GetNode(id).(*SubTree)
+		if !ok {
+			return nil, fmt.Errorf("subtree node %s not found in tree %s", id, treeKey(tree))
+		}
+		subTrees = append(subTrees, sub)
+	}
+	var node = tree.GetNode(ids[len(ids)-1])
+	if node == nil {
+		return nil, fmt.Errorf("node %s not found in tree %s", scope, treeKey(tree))
+	}
+	if len(subTrees) == 0 {
+		return node._baseNode(), nil
+	}
+	return &scopedNode{BaseNode: node._baseNode(), subTrees: subTrees, scope: scope}, nil
+}
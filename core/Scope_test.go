@@ -0,0 +1,80 @@
+package core_test
+
+import (
+	"testing"
+
+	b3 "github.com/magicsea/behavior3go"
+	. "github.com/magicsea/behavior3go/config"
+	. "github.com/magicsea/behavior3go/core"
+)
+
+//主树用两个SubTree节点引用同一个子树，返回恢复子树加载的函数
+func createScopeTrees(subtree *BehaviorTree) (*BehaviorTree, func()) {
+	var restore = useSubTrees(subtree)
+	return createTestTree("main", "seq",
+		BTNodeCfg{Id: "seq", Name: "Sequence", Category: b3.COMPOSITE, Children: []string{"first", "second"}},
+		subTreeNode("first", subtree.GetTitile(), nil),
+		subTreeNode("second", subtree.GetTitile(), nil),
+	), restore
+}
+
+func TestSubTreeInstanceMemory(t *testing.T) {
+	tree, restore := createScopeTrees(createTestTree("once", "limit",
+		BTNodeCfg{Id: "limit", Name: "Limiter", Category: b3.DECORATOR, Child: "ok", Properties: map[string]interface{}{"maxLoop": 1.0}},
+		BTNodeCfg{Id: "ok", Name: "Succeeder", Category: b3.ACTION},
+	))
+	defer restore()
+	var blackboard = NewBlackboard()
+
+	// each reference has its own limiter
+	if status := tree.Tick(&npc{}, blackboard); status != b3.SUCCESS {
+		t.Error("status", status)
+	}
+	if i := blackboard.GetInt("i", tree.GetID(), "second/limit"); i != 1 {
+		t.Error("second limiter:", i)
+	}
+	if status := tree.Tick(&npc{}, blackboard); status != b3.FAILURE {
+		t.Error("status", status)
+	}
+}
+
+func TestSubTreeInstanceHalt(t *testing.T) {
+	tree, restore := createScopeTrees(createTestTree("job", "mseq",
+		BTNodeCfg{Id: "mseq", Name: "MemSequence", Category: b3.COMPOSITE, Children: []string{"ok", "run"}},
+		BTNodeCfg{Id: "ok", Name: "Succeeder", Category: b3.ACTION},
+		BTNodeCfg{Id: "run", Name: "Runner", Category: b3.ACTION},
+	))
+	defer restore()
+	var blackboard = NewBlackboard()
+	var target = &npc{}
+
+	tree.Tick(target, blackboard)
+	if child := blackboard.GetInt("runningChild", tree.GetID(), "first/mseq"); child != 1 {
+		t.Error("runningChild:", child)
+	}
+	if len(blackboard.GetNodeScopes(tree.GetID())) == 0 || blackboard.GetBool("isOpen", tree.GetID(), "mseq") {
+		t.Error("memory not scoped by the subtree path")
+	}
+
+	// the open nodes are saved with their subtree path
+	snapshot, err := blackboard.Snapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if open := snapshot.Trees[0].OpenNodes; len(open) != 4 || open[3] != "first/run" {
+		t.Error("open nodes:", open)
+	}
+	blackboard = NewBlackboard()
+	if err := blackboard.Restore(snapshot, tree); err != nil {
+		t.Fatal(err)
+	}
+
+	// the halt runs in the scope the node was opened in
+	tree.Abort(target, blackboard)
+	if blackboard.GetBool("isOpen", tree.GetID(), "first/mseq") || blackboard.GetBool("isOpen", tree.GetID(), "first/run") {
+		t.Error("subtree nodes not halted")
+	}
+	if child := blackboard.GetInt("runningChild", tree.GetID(), "first/mseq"); child != 0 {
+		t.Error("runningChild after abort:", child)
+	}
+}
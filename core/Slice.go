@@ -0,0 +1,145 @@
+package core
+
+import (
+	"time"
+
+	b3 "github.com/magicsea/behavior3go"
+)
+
+/**
+ * Time-sliced ticks.
+ *
+ * With a budget in `TickOptions`, the traversal stops before entering a
+ * node once the budget is spent, as long as at least one node completed.
+ * The tick returns `b3.RUNNING`, the composites on the path save the index
+ * of the child to resume from in their node memory (`resumeChild`), and
+ * the next tick goes straight back there, so the tree behaves as if it ran
+ * without a break. Composites must use `tick.ResumeChild` and
+ * `tick.SuspendChild` for this, see `Sequence` and `Priority`.
+**/
+
+/**
+ * Whether the traversal was suspended because the budget is spent. Once
+ * true, every node returns `b3.RUNNING` without being executed.
+**/
+func (this *Tick) IsSuspended() bool {
+	return this._suspended
+}
+
+//预算用完时挂起，至少完成一个节点保证每次都有进展
+func (this *Tick) _shouldSuspend() bool {
+	if this._suspended {
+		return true
+	}
+	if this.deadline.IsZero() || this._completed == 0 {
+		return false
+	}
+	if time.Now().Before(this.deadline) {
+		return false
+	}
+	this._suspended = true
+	return true
+}
+
+/**
+ * Saves the child a composite must resume from, when the traversal was
+ * suspended under it. Does nothing if the tick is not suspended.
+ *
+ * @method SuspendChild
+ * @param {IBaseNode} node The composite.
+ * @param {Integer} index The index of the child being executed.
+**/
+func (this *Tick) SuspendChild(node IBaseNode, index int) {
+	if !this._suspended {
+		return
+	}
+	this.Blackboard.Set("resumeChild", index, this.tree.id, this.GetNodeScope(node))
+}
+
+/**
+ * The child a composite must start from: the one saved by `SuspendChild`
+ * when the last tick was suspended, 0 otherwise. The saved index is used
+ * only once.
+ *
+ * @method ResumeChild
+ * @param {IBaseNode} node The composite.
+ * @return {Integer} The index of the first child to execute.
+**/
+func (this *Tick) ResumeChild(node IBaseNode) int {
+	var index, ok = this.Blackboard.Get("resumeChild", this.tree.id, this.GetNodeScope(node)).(int)
+	if !ok {
+		return 0
+	}
+	this.Blackboard.Delete("resumeChild", this.tree.id, this.GetNodeScope(node))
+	return index
+}
+
+/**
+ * Ticks the tree, spending at most `budget` of wall time (plus the time of
+ * the node running when it is spent). Returns the status of the tree and
+ * whether the traversal completed; if not, the status is `b3.RUNNING` and
+ * the next call resumes where this one stopped.
+ *
+ * @method TickSliced
+ * @param {Object} target A target object.
+ * @param {Blackboard} blackboard An instance of blackboard object.
+ * @param {Duration} budget The time slice.
+ * @return {Constant} The tick signal state.
+ * @return {Boolean} Whether the traversal completed.
+**/
+func (this *BehaviorTree) TickSliced(target interface{}, blackboard *Blackboard, budget time.Duration) (b3.Status, bool) {
+	var status = this.TickWith(target, blackboard, TickOptions{Budget: budget})
+	return status, !this.IsSuspended(blackboard)
+}
+
+/**
+ * Whether the last tick on the blackboard was suspended and the next one
+ * resumes it.
+**/
+func (this *BehaviorTree) IsSuspended(blackboard *Blackboard) bool {
+	return blackboard._getTreeData(this.id).Suspended
+}
+
+//挂起时记录路径上的节点，tree不做关闭处理
+func (this *BehaviorTree) _suspend(tick *Tick, treeData *TreeData, currOpenNodes []IBaseNode) {
+	treeData.Suspended = true
+	for _, node := range currOpenNodes {
+		if !containsNode(treeData.SuspendedNodes, node) {
+			treeData.SuspendedNodes = append(treeData.SuspendedNodes, node)
+		}
+	}
+}
+
+//完成或者放弃挂起的遍历，清理没用上的resumeChild
+func (this *BehaviorTree) _clearSuspended(tick *Tick, treeData *TreeData) {
+	for _, node := range treeData.SuspendedNodes {
+		tick.Blackboard._getMemory(this.id, nodeScope(node)).Remove("resumeChild")
+	}
+	treeData.Suspended = false
+	treeData.SuspendedNodes = nil
+}
+
+//打断挂起遍历中的子树，子树上面的节点下次从子树重新进入
+func (this *BehaviorTree) _abortSuspended(tick *Tick, treeData *TreeData, subTreeID string) bool {
+	for i, node := range treeData.SuspendedNodes {
+		if isSubTreeNode(node, subTreeID) {
+			var nodes = treeData.SuspendedNodes[i:]
+			haltNodes(tick, nodes)
+			for _, n := range nodes[1:] {
+				tick.Blackboard._getMemory(this.id, nodeScope(n)).Remove("resumeChild")
+			}
+			treeData.SuspendedNodes = treeData.SuspendedNodes[:i:i]
+			return true
+		}
+	}
+	return false
+}
+
+func containsNode(nodes []IBaseNode, node IBaseNode) bool {
+	for _, n := range nodes {
+		if nodeScope(n) == nodeScope(node) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,117 @@
+package core_test
+
+import (
+	"testing"
+	"time"
+
+	b3 "github.com/magicsea/behavior3go"
+	. "github.com/magicsea/behavior3go/config"
+	. "github.com/magicsea/behavior3go/core"
+)
+
+func createSliceTree() *BehaviorTree {
+	return createTestTree("slice", "seq",
+		BTNodeCfg{Id: "seq", Name: "Sequence", Category: b3.COMPOSITE, Children: []string{"a", "pri", "d"}},
+		BTNodeCfg{Id: "a", Name: "SlowAction", Category: b3.ACTION},
+		BTNodeCfg{Id: "pri", Name: "Priority", Category: b3.COMPOSITE, Children: []string{"no", "b", "c"}},
+		BTNodeCfg{Id: "no", Name: "Failer", Category: b3.ACTION},
+		BTNodeCfg{Id: "b", Name: "SlowAction", Category: b3.ACTION},
+		BTNodeCfg{Id: "c", Name: "SlowAction", Category: b3.ACTION},
+		BTNodeCfg{Id: "d", Name: "SlowAction", Category: b3.ACTION},
+	)
+}
+
+func TestTickSliced(t *testing.T) {
+	var tree = createSliceTree()
+	var target = sliceTarget{}
+	var blackboard = NewBlackboard()
+
+	var slices int
+	for {
+		slices++
+		status, done := tree.TickSliced(target, blackboard, time.Millisecond)
+		if done {
+			if status != b3.SUCCESS {
+				t.Fatal("status", status)
+			}
+			break
+		}
+		if status != b3.RUNNING || !tree.IsSuspended(blackboard) {
+			t.Fatal("slice", slices, "status", status)
+		}
+		if slices > 10 {
+			t.Fatal("never completed")
+		}
+	}
+	if slices < 3 {
+		t.Error("slices:", slices)
+	}
+	// each node runs once, as in a single tick
+	for _, id := range []string{"a", "b", "d"} {
+		if target[id] != 1 {
+			t.Error(id, "ticks:", target[id])
+		}
+	}
+	if target["c"] != 0 {
+		t.Error("c ticks:", target["c"])
+	}
+
+	// a tick without budget starts over
+	tree.Tick(target, blackboard)
+	if target["a"] != 2 || target["d"] != 2 {
+		t.Error("ticks", target)
+	}
+}
+
+func TestTickSlicedAbort(t *testing.T) {
+	var tree = createSliceTree()
+	var target = sliceTarget{}
+	var blackboard = NewBlackboard()
+
+	if _, done := tree.TickSliced(target, blackboard, time.Millisecond); done {
+		t.Fatal("completed in one slice")
+	}
+	tree.Abort(target, blackboard)
+	if tree.IsSuspended(blackboard) {
+		t.Fatal("still suspended")
+	}
+	if status := tree.Tick(target, blackboard); status != b3.SUCCESS {
+		t.Fatal("status", status)
+	}
+	if target["a"] != 2 {
+		t.Error("a ticks:", target["a"])
+	}
+}
+
+func TestTickSlicedAbortSubTree(t *testing.T) {
+	defer useSubTrees(createSliceTree())()
+	var tree = createTestTree("main", "seq",
+		BTNodeCfg{Id: "seq", Name: "Sequence", Category: b3.COMPOSITE, Children: []string{"job"}},
+		subTreeNode("job", "slice", nil),
+	)
+	var target = sliceTarget{}
+	var blackboard = NewBlackboard()
+
+	if _, done := tree.TickSliced(target, blackboard, time.Millisecond); done {
+		t.Fatal("completed in one slice")
+	}
+	// the suspended composites are not SubTree nodes
+	if tree.AbortSubTree(target, blackboard, "seq") || tree.AbortSubTree(target, blackboard, "pri") {
+		t.Error("aborted a composite")
+	}
+	if !tree.AbortSubTree(target, blackboard, "job") {
+		t.Fatal("suspended subtree not found")
+	}
+	for i := 0; i < 10; i++ {
+		if status, done := tree.TickSliced(target, blackboard, time.Millisecond); done {
+			if status != b3.SUCCESS {
+				t.Fatal("status", status)
+			}
+			break
+		}
+	}
+	// the subtree started again from its root
+	if target["a"] != 2 || target["d"] != 1 {
+		t.Error("ticks:", target)
+	}
+}
@@ -0,0 +1,315 @@
+package core
+
+import (
+	"bytes"
+	"encoding/gob"
+	"fmt"
+	"sort"
+	"time"
+)
+
+/**
+ * The content of a blackboard, with the state of the running trees, which
+ * can be written to JSON (`json.Marshal`) or binary (`MarshalBinary`) and
+ * restored in another process with `Blackboard.Restore`.
+ *
+ * The values are stored as `TypedValue`: values of custom types must be
+ * registered with `RegisterValueType` to be restored with their type.
+ * The expiry times of `SetWithTTL` are kept as absolute times, of the
+ * clock they were set on.
+ *
+ * @module b3
+ * @class BlackboardSnapshot
+**/
+type BlackboardSnapshot struct {
+	Base        map[string]TypedValue `json:"base,omitempty"`
+	BaseExpires map[string]time.Time  `json:"baseExpires,omitempty"`
+	Trees       []TreeSnapshot        `json:"trees,omitempty"`
+}
+
+/**
+ * The memory of a tree scope in a `BlackboardSnapshot`. The runtime ID of
+ * a tree changes on every load, so the tree is identified by the ID of its
+ * config (`Tree`), the open nodes by their node scopes (see
+ * `Tick.GetNodeScope`).
+**/
+type TreeSnapshot struct {
+	Scope          string                           `json:"scope"`
+	Tree           string                           `json:"tree,omitempty"`
+	Memory         map[string]TypedValue            `json:"memory,omitempty"`
+	Nodes          map[string]map[string]TypedValue `json:"nodes,omitempty"`
+	Expires        map[string]time.Time             `json:"expires,omitempty"`
+	NodeExpires    map[string]map[string]time.Time  `json:"nodeExpires,omitempty"`
+	OpenNodes      []string                         `json:"openNodes,omitempty"`
+	Suspended      bool                             `json:"suspended,omitempty"`
+	SuspendedNodes []string                         `json:"suspendedNodes,omitempty"`
+	TraversalDepth int                              `json:"traversalDepth,omitempty"`
+	DepthLimitHits int                              `json:"depthLimitHits,omitempty"`
+}
+
+//gob编码用，避免调用回MarshalBinary
+type blackboardSnapshotData BlackboardSnapshot
+
+//二进制格式，gob
+func (this *BlackboardSnapshot) MarshalBinary() ([]byte, error) {
+	var buffer bytes.Buffer
+	if err := gob.NewEncoder(&buffer).Encode((*blackboardSnapshotData)(this)); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+func (this *BlackboardSnapshot) UnmarshalBinary(data []byte) error {
+	return gob.NewDecoder(bytes.NewReader(data)).Decode((*blackboardSnapshotData)(this))
+}
+
+//树的标识，用配置的ID，没有时用标题
+func treeKey(tree *BehaviorTree) string {
+	if tree.dumpInfo != nil && len(tree.dumpInfo.ID) > 0 {
+		return tree.dumpInfo.ID
+	}
+	return tree.title
+}
+
+func snapshotMemory(memory *Memory) (map[string]TypedValue, error) {
+	var values = memory._copy()
+	if len(values) == 0 {
+		return nil, nil
+	}
+	var typed = make(map[string]TypedValue, len(values))
+	for key, value := range values {
+		var v, err = NewTypedValue(value)
+		if err != nil {
+			return nil, fmt.Errorf("key %s: %v", key, err)
+		}
+		typed[key] = v
+	}
+	return typed, nil
+}
+
+func restoreMemory(memory *Memory, values map[string]TypedValue) error {
+	for key, typed := range values {
+		var value, err = typed.Value()
+		if err != nil {
+			return fmt.Errorf("key %s: %v", key, err)
+		}
+		memory._memory[key] = value
+	}
+	return nil
+}
+
+/**
+ * Takes a snapshot of the blackboard: the global, per tree and per node
+ * memories and the open nodes of the trees ticked with it, not the
+ * parent. Must not be called while a tree is ticked with the blackboard.
+ *
+ * @method Snapshot
+ * @return {BlackboardSnapshot} The snapshot.
+**/
+func (this *Blackboard) Snapshot() (*BlackboardSnapshot, error) {
+	var snapshot = &BlackboardSnapshot{}
+	var err error
+	if snapshot.Base, err = snapshotMemory(this._baseMemory); err != nil {
+		return nil, fmt.Errorf("Blackboard.Snapshot: %v", err)
+	}
+	snapshot.BaseExpires = this._baseMemory._copyExpires()
+
+	var scopes []string
+	var treeMemories = make(map[string]*TreeMemory)
+	this._rlock()
+	for scope, treeMem := range this._treeMemory {
+		scopes = append(scopes, scope)
+		treeMemories[scope] = treeMem
+	}
+	this._runlock()
+	sort.Strings(scopes)
+
+	for _, scope := range scopes {
+		var treeMem = treeMemories[scope]
+		var tree = TreeSnapshot{Scope: scope}
+		if tree.Memory, err = snapshotMemory(treeMem.Memory); err != nil {
+			return nil, fmt.Errorf("Blackboard.Snapshot: tree %s: %v", scope, err)
+		}
+		tree.Expires = treeMem.Memory._copyExpires()
+
+		this._rlock()
+		var nodeMemories = make(map[string]*Memory, len(treeMem._nodeMemory))
+		for nodeScope, memory := range treeMem._nodeMemory {
+			nodeMemories[nodeScope] = memory
+		}
+		this._runlock()
+		for nodeScope, memory := range nodeMemories {
+			var values, err = snapshotMemory(memory)
+			if err != nil {
+				return nil, fmt.Errorf("Blackboard.Snapshot: tree %s node %s: %v", scope, nodeScope, err)
+			}
+			if values == nil {
+				continue
+			}
+			if tree.Nodes == nil {
+				tree.Nodes = make(map[string]map[string]TypedValue)
+			}
+			tree.Nodes[nodeScope] = values
+			if expires := memory._copyExpires(); expires != nil {
+				if tree.NodeExpires == nil {
+					tree.NodeExpires = make(map[string]map[string]time.Time)
+				}
+				tree.NodeExpires[nodeScope] = expires
+			}
+		}
+
+		var treeData = treeMem._treeData
+		if treeData.tree != nil {
+			tree.Tree = treeKey(treeData.tree)
+		}
+		tree.OpenNodes = openNodeScopes(treeData.OpenNodes)
+		tree.Suspended = treeData.Suspended
+		tree.SuspendedNodes = openNodeScopes(treeData.SuspendedNodes)
+		tree.TraversalDepth = treeData.TraversalDepth
+		tree.DepthLimitHits = treeData.DepthLimitHits
+		if len(tree.OpenNodes) == 0 {
+			tree.OpenNodes = nil
+		}
+		if len(tree.SuspendedNodes) == 0 {
+			tree.SuspendedNodes = nil
+		}
+		snapshot.Trees = append(snapshot.Trees, tree)
+	}
+	return snapshot, nil
+}
+
+/**
+ * Replaces the content of the blackboard by a snapshot. The tree scopes of
+ * the snapshot are moved to the given trees, matched by config ID (or
+ * title), and their open nodes are resolved on these trees, so the next
+ * tick resumes the running actions. The scopes of unknown trees are
+ * restored as is, an error is returned if they have open nodes.
+ *
+ * The subscribers are not notified. Must not be called while a tree is
+ * ticked with the blackboard.
+ *
+ * @method Restore
+ * @param {BlackboardSnapshot} snapshot The snapshot.
+ * @param {BehaviorTree} trees The trees ticked with the blackboard.
+**/
+func (this *Blackboard) Restore(snapshot *BlackboardSnapshot, trees ...*BehaviorTree) error {
+	var byKey = make(map[string]*BehaviorTree, len(trees))
+	for _, tree := range trees {
+		byKey[treeKey(tree)] = tree
+	}
+
+	var ttlMemories = make(map[*Memory]ttlScope)
+	var baseMemory = this._newMemory()
+	if err := restoreMemory(baseMemory, snapshot.Base); err != nil {
+		return fmt.Errorf("Blackboard.Restore: %v", err)
+	}
+	restoreExpires(baseMemory, snapshot.BaseExpires, ttlMemories, ttlScope{})
+	var treeMemories = make(map[string]*TreeMemory, len(snapshot.Trees))
+	for i := range snapshot.Trees {
+		var saved = &snapshot.Trees[i]
+		var scope = saved.Scope
+		var tree *BehaviorTree
+		if len(saved.Tree) > 0 {
+			tree = byKey[saved.Tree]
+		}
+		if tree != nil {
+			scope = tree.id
+		}
+
+		var treeMem = this._newTreeMemory()
+		if err := restoreMemory(treeMem.Memory, saved.Memory); err != nil {
+			return fmt.Errorf("Blackboard.Restore: tree %s: %v", saved.Scope, err)
+		}
+		restoreExpires(treeMem.Memory, saved.Expires, ttlMemories, ttlScope{scope, ""})
+		for nodeScope, values := range saved.Nodes {
+			var memory = this._newMemory()
+			if err := restoreMemory(memory, values); err != nil {
+				return fmt.Errorf("Blackboard.Restore: tree %s node %s: %v", saved.Scope, nodeScope, err)
+			}
+			restoreExpires(memory, saved.NodeExpires[nodeScope], ttlMemories, ttlScope{scope, nodeScope})
+			treeMem._nodeMemory[nodeScope] = memory
+		}
+
+		var treeData = treeMem._treeData
+		var err error
+		if treeData.OpenNodes, err = resolveNodes(tree, saved.OpenNodes); err != nil {
+			return fmt.Errorf("Blackboard.Restore: tree %s: %v", saved.Scope, err)
+		}
+		if treeData.SuspendedNodes, err = resolveNodes(tree, saved.SuspendedNodes); err != nil {
+			return fmt.Errorf("Blackboard.Restore: tree %s: %v", saved.Scope, err)
+		}
+		if len(treeData.SuspendedNodes) == 0 {
+			treeData.SuspendedNodes = nil
+		}
+		treeData.Suspended = saved.Suspended
+		treeData.TraversalDepth = saved.TraversalDepth
+		treeData.DepthLimitHits = saved.DepthLimitHits
+		treeData.tree = tree
+		treeMemories[scope] = treeMem
+	}
+
+	this._lock()
+	this._baseMemory = baseMemory
+	this._treeMemory = treeMemories
+	this._ttlMemories = ttlMemories
+	this._unlock()
+	return nil
+}
+
+func restoreExpires(memory *Memory, expires map[string]time.Time, ttlMemories map[*Memory]ttlScope, scope ttlScope) {
+	if len(expires) == 0 {
+		return
+	}
+	memory._expires = make(map[string]time.Time, len(expires))
+	for key, t := range expires {
+		if _, ok := memory._memory[key]; ok {
+			memory._expires[key] = t
+		}
+	}
+	ttlMemories[memory] = scope
+}
+
+//节点作用域还原为树中的节点
+func resolveNodes(tree *BehaviorTree, ids []string) ([]IBaseNode, error) {
+	var nodes = make([]IBaseNode, 0, len(ids))
+	if len(ids) == 0 {
+		return nodes, nil
+	}
+	if tree == nil {
+		return nil, fmt.Errorf("no tree to resolve the open nodes")
+	}
+	for _, id := range ids {
+		var node, err = resolveScope(tree, id)
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, node)
+	}
+	return nodes, nil
+}
+
+func (this *Blackboard) _newMemory() *Memory {
+	if this._mutex != nil {
+		return newSyncMemory()
+	}
+	return NewMemory()
+}
+
+func (this *Blackboard) _newTreeMemory() *TreeMemory {
+	if this._mutex != nil {
+		return newSyncTreeMemory()
+	}
+	return NewTreeMemory()
+}
+
+func (this *Blackboard) _rlock() {
+	if this._mutex != nil {
+		this._mutex.RLock()
+	}
+}
+
+func (this *Blackboard) _runlock() {
+	if this._mutex != nil {
+		this._mutex.RUnlock()
+	}
+}
@@ -0,0 +1,103 @@
+package core_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	b3 "github.com/magicsea/behavior3go"
+	. "github.com/magicsea/behavior3go/config"
+	. "github.com/magicsea/behavior3go/core"
+)
+
+type position struct {
+	X, Y int
+}
+
+func createPatrolTree() *BehaviorTree {
+	var tree = createTestTree("patrol", "seq",
+		BTNodeCfg{Id: "seq", Name: "MemSequence", Category: b3.COMPOSITE, Children: []string{"move", "wait"}},
+		BTNodeCfg{Id: "move", Name: "SlowAction", Category: b3.ACTION},
+		BTNodeCfg{Id: "wait", Name: "Wait", Category: b3.ACTION, Properties: map[string]interface{}{"milliseconds": 1000.0}},
+	)
+	tree.SetClock(NewManualClock(time.Unix(100, 0)))
+	return tree
+}
+
+func TestSnapshotRestore(t *testing.T) {
+	RegisterValueType(position{})
+	var tree = createPatrolTree()
+	var target = sliceTarget{}
+	var blackboard = NewBlackboard()
+	blackboard.SetMem("home", position{1, 2})
+	blackboard.SetMem("level", int32(3))
+
+	if status := tree.Tick(target, blackboard); status != b3.RUNNING {
+		t.Fatal("status", status)
+	}
+	snapshot, err := blackboard.Snapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, encoding := range []string{"json", "binary"} {
+		var restored = &BlackboardSnapshot{}
+		switch encoding {
+		case "json":
+			data, err := json.Marshal(snapshot)
+			if err == nil {
+				err = json.Unmarshal(data, restored)
+			}
+			if err != nil {
+				t.Fatal(encoding, err)
+			}
+		case "binary":
+			data, err := snapshot.MarshalBinary()
+			if err == nil {
+				err = restored.UnmarshalBinary(data)
+			}
+			if err != nil {
+				t.Fatal(encoding, err)
+			}
+		}
+
+		// another process: the tree is loaded again with a new runtime id
+		var other = createPatrolTree()
+		var otherTarget = sliceTarget{}
+		var otherBlackboard = NewBlackboard()
+		if err := otherBlackboard.Restore(restored, other); err != nil {
+			t.Fatal(encoding, err)
+		}
+		if home, ok := Get[position](otherBlackboard, "home", "", ""); !ok || home != (position{1, 2}) {
+			t.Error(encoding, "home", home)
+		}
+		if level, ok := otherBlackboard.GetMem("level").(int32); !ok || level != 3 {
+			t.Error(encoding, "level", otherBlackboard.GetMem("level"))
+		}
+
+		// the wait is still running and the move is not done again
+		if status, err := other.TickE(otherTarget, otherBlackboard); status != b3.RUNNING {
+			t.Error(encoding, "status", status, err)
+		}
+		other.GetClock().(*ManualClock).Advance(2 * time.Second)
+		if status := other.Tick(otherTarget, otherBlackboard); status != b3.SUCCESS {
+			t.Error(encoding, "status", status)
+		}
+		if otherTarget["move"] != 0 {
+			t.Error(encoding, "move ticks", otherTarget["move"])
+		}
+	}
+}
+
+func TestRestoreUnknownTree(t *testing.T) {
+	var tree = createPatrolTree()
+	var blackboard = NewBlackboard()
+	tree.Tick(sliceTarget{}, blackboard)
+	snapshot, err := blackboard.Snapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := NewBlackboard().Restore(snapshot); err == nil {
+		t.Error("restored open nodes without the tree")
+	}
+}
@@ -0,0 +1,303 @@
+package core
+
+import (
+	"reflect"
+	"sync"
+	"time"
+
+	b3 "github.com/magicsea/behavior3go"
+)
+
+/**
+ * Execution statistics of a node, for all the targets ticking the tree.
+ *
+ * - **Ticks** Number of times the node was executed, then the count of
+ *   each returned status.
+ * - **TotalTime**, **MaxTime** Wall time of an execution of the node,
+ *   children included.
+ * - **Opens**, **OpenTime**, **MaxOpenTime** Number of times the node was
+ *   opened and the time it stayed open until closed, on the clock of the
+ *   tree.
+**/
+type NodeStats struct {
+	ID    string
+	Name  string
+	Title string
+
+	Ticks   uint64
+	Success uint64
+	Failure uint64
+	Running uint64
+	Error   uint64
+
+	TotalTime time.Duration
+	MaxTime   time.Duration
+
+	Opens       uint64
+	OpenTime    time.Duration
+	MaxOpenTime time.Duration
+}
+
+/**
+ * Execution statistics of a tree, see `BehaviorTree.GetStats`. The nodes
+ * are indexed by node ID, nodes of subtrees included.
+**/
+type TreeStats struct {
+	TreeID string
+	Title  string
+
+	Ticks   uint64
+	Success uint64
+	Failure uint64
+	Running uint64
+	Error   uint64
+
+	TotalTime time.Duration
+	MaxTime   time.Duration
+
+	Nodes map[string]NodeStats
+}
+
+//累加另一个分片的统计
+func (this *NodeStats) merge(other *NodeStats) {
+	this.Ticks += other.Ticks
+	this.Success += other.Success
+	this.Failure += other.Failure
+	this.Running += other.Running
+	this.Error += other.Error
+	this.TotalTime += other.TotalTime
+	if other.MaxTime > this.MaxTime {
+		this.MaxTime = other.MaxTime
+	}
+	this.Opens += other.Opens
+	this.OpenTime += other.OpenTime
+	if other.MaxOpenTime > this.MaxOpenTime {
+		this.MaxOpenTime = other.MaxOpenTime
+	}
+}
+
+func (this *NodeStats) count(status b3.Status, elapsed time.Duration) {
+	this.Ticks++
+	switch status {
+	case b3.SUCCESS:
+		this.Success++
+	case b3.FAILURE:
+		this.Failure++
+	case b3.RUNNING:
+		this.Running++
+	case b3.ERROR:
+		this.Error++
+	}
+	this.TotalTime += elapsed
+	if elapsed > this.MaxTime {
+		this.MaxTime = elapsed
+	}
+}
+
+//统计分片数，同时tick同一棵树的agent按黑板分散到各个分片
+const statsShards = 16
+
+//一个分片的统计，snapshot时合并
+type statsShard struct {
+	mutex  sync.Mutex
+	tree   NodeStats
+	nodes  map[string]*NodeStats
+	starts map[*Tick]time.Time
+	//打开中的节点的打开时间，按黑板和节点作用域
+	opens map[*Blackboard]map[string]time.Time
+}
+
+//统计收集，作为observer注册在树上
+type statsCollector struct {
+	BaseTickObserver
+	shards [statsShards]statsShard
+}
+
+func newStatsCollector() *statsCollector {
+	var collector = &statsCollector{}
+	collector.reset()
+	return collector
+}
+
+func (this *statsCollector) reset() {
+	for i := range this.shards {
+		var shard = &this.shards[i]
+		shard.mutex.Lock()
+		shard.tree = NodeStats{}
+		shard.nodes = make(map[string]*NodeStats)
+		shard.starts = make(map[*Tick]time.Time)
+		shard.opens = make(map[*Blackboard]map[string]time.Time)
+		shard.mutex.Unlock()
+	}
+}
+
+//黑板所在的分片
+func (this *statsCollector) shard(blackboard *Blackboard) *statsShard {
+	var p = reflect.ValueOf(blackboard).Pointer()
+	return &this.shards[(p>>4)%statsShards]
+}
+
+func (this *statsCollector) OnTickBegin(tick *Tick) {
+	var shard = this.shard(tick.Blackboard)
+	shard.mutex.Lock()
+	shard.starts[tick] = time.Now()
+	shard.mutex.Unlock()
+}
+
+func (this *statsCollector) OnTickEnd(tick *Tick, status b3.Status) {
+	var shard = this.shard(tick.Blackboard)
+	shard.mutex.Lock()
+	defer shard.mutex.Unlock()
+	if start, ok := shard.starts[tick]; ok {
+		delete(shard.starts, tick)
+		shard.tree.count(status, time.Since(start))
+	}
+	shard.prune(tick)
+}
+
+func (this *statsCollector) OnNodeEvent(event *NodeEvent) {
+	var shard = this.shard(event.Tick.Blackboard)
+	shard.mutex.Lock()
+	defer shard.mutex.Unlock()
+	switch event.Type {
+	case EVENT_OPEN:
+		shard.node(event).Opens++
+		var opens = shard.opens[event.Tick.Blackboard]
+		if opens == nil {
+			opens = make(map[string]time.Time)
+			shard.opens[event.Tick.Blackboard] = opens
+		}
+		opens[event.Scope] = event.Time
+	case EVENT_HALT, EVENT_CLOSE:
+		var opens = shard.opens[event.Tick.Blackboard]
+		if opened, ok := opens[event.Scope]; ok {
+			delete(opens, event.Scope)
+			var stats = shard.node(event)
+			var elapsed = event.Time.Sub(opened)
+			stats.OpenTime += elapsed
+			if elapsed > stats.MaxOpenTime {
+				stats.MaxOpenTime = elapsed
+			}
+		}
+	case EVENT_EXIT:
+		shard.node(event).count(event.Status, event.Elapsed)
+	}
+}
+
+//tick结束时，丢弃不再打开的节点（例如没有关闭就被清除的内存），必须持有锁
+func (this *statsShard) prune(tick *Tick) {
+	var opens = this.opens[tick.Blackboard]
+	if len(opens) == 0 {
+		delete(this.opens, tick.Blackboard)
+		return
+	}
+	var treeData = tick.Blackboard._getTreeData(tick.tree.id)
+	var open = make(map[string]bool, len(treeData.OpenNodes)+len(treeData.SuspendedNodes))
+	for _, node := range treeData.OpenNodes {
+		open[nodeScope(node)] = true
+	}
+	for _, node := range treeData.SuspendedNodes {
+		open[nodeScope(node)] = true
+	}
+	for scope := range opens {
+		if !open[scope] {
+			delete(opens, scope)
+		}
+	}
+	if len(opens) == 0 {
+		delete(this.opens, tick.Blackboard)
+	}
+}
+
+//必须持有锁
+func (this *statsShard) node(event *NodeEvent) *NodeStats {
+	var stats, ok = this.nodes[event.ID]
+	if !ok {
+		stats = &NodeStats{ID: event.ID, Name: event.Name, Title: event.Title}
+		this.nodes[event.ID] = stats
+	}
+	return stats
+}
+
+func (this *statsCollector) snapshot() TreeStats {
+	var tree NodeStats
+	var nodes = make(map[string]*NodeStats)
+	for i := range this.shards {
+		var shard = &this.shards[i]
+		shard.mutex.Lock()
+		tree.merge(&shard.tree)
+		for id, node := range shard.nodes {
+			if nodes[id] == nil {
+				nodes[id] = &NodeStats{ID: node.ID, Name: node.Name, Title: node.Title}
+			}
+			nodes[id].merge(node)
+		}
+		shard.mutex.Unlock()
+	}
+
+	var stats = TreeStats{
+		Ticks:     tree.Ticks,
+		Success:   tree.Success,
+		Failure:   tree.Failure,
+		Running:   tree.Running,
+		Error:     tree.Error,
+		TotalTime: tree.TotalTime,
+		MaxTime:   tree.MaxTime,
+		Nodes:     make(map[string]NodeStats, len(nodes)),
+	}
+	for id, node := range nodes {
+		stats.Nodes[id] = *node
+	}
+	return stats
+}
+
+/**
+ * Starts or stops collecting the execution statistics of the tree. Stopping
+ * keeps the statistics collected so far.
+**/
+func (this *BehaviorTree) EnableStats(enable bool) {
+	this.statsMutex.Lock()
+	defer this.statsMutex.Unlock()
+	if enable == this.statsEnabled {
+		return
+	}
+	this.statsEnabled = enable
+	if this.stats == nil {
+		this.stats = newStatsCollector()
+	}
+	if enable {
+		this.AddObserver(this.stats)
+	} else {
+		this.RemoveObserver(this.stats)
+	}
+}
+
+/**
+ * A snapshot of the statistics collected since `EnableStats` or the last
+ * `ResetStats`.
+**/
+func (this *BehaviorTree) GetStats() TreeStats {
+	this.statsMutex.Lock()
+	var collector = this.stats
+	this.statsMutex.Unlock()
+
+	var stats TreeStats
+	if collector != nil {
+		stats = collector.snapshot()
+	} else {
+		stats.Nodes = make(map[string]NodeStats)
+	}
+	stats.TreeID = this.id
+	stats.Title = this.title
+	return stats
+}
+
+func (this *BehaviorTree) ResetStats() {
+	this.statsMutex.Lock()
+	var collector = this.stats
+	this.statsMutex.Unlock()
+
+	if collector != nil {
+		collector.reset()
+	}
+}
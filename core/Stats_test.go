@@ -0,0 +1,45 @@
+package core_test
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/magicsea/behavior3go/core"
+)
+
+func TestStatsOpenTime(t *testing.T) {
+	var tree = createRunTree()
+	var clock = NewManualClock(time.Unix(100, 0))
+	tree.SetClock(clock)
+	tree.EnableStats(true)
+
+	// the agents are spread on the shards, the snapshot adds them up
+	const agents = 40
+	var blackboards []*Blackboard
+	for i := 0; i < agents; i++ {
+		var blackboard = NewBlackboard()
+		blackboards = append(blackboards, blackboard)
+		tree.Tick(nil, blackboard)
+	}
+	clock.Advance(time.Second)
+	tree.Abort(nil, blackboards[0])
+
+	var stats = tree.GetStats()
+	if stats.Ticks != agents || stats.Running != agents || stats.Nodes["ok"].Success != agents {
+		t.Error("ticks", stats.Ticks, "running", stats.Running, "ok", stats.Nodes["ok"].Success)
+	}
+	var run = stats.Nodes["run"]
+	if run.Opens != agents || run.OpenTime != time.Second || run.MaxOpenTime != time.Second {
+		t.Error("run:", run.Opens, run.OpenTime, run.MaxOpenTime)
+	}
+
+	tree.ResetStats()
+	if stats := tree.GetStats(); stats.Ticks != 0 || len(stats.Nodes) != 0 {
+		t.Error("not reset:", stats.Ticks, len(stats.Nodes))
+	}
+	tree.EnableStats(false)
+	tree.Tick(nil, blackboards[1])
+	if stats := tree.GetStats(); stats.Ticks != 0 {
+		t.Error("disabled stats counted:", stats.Ticks)
+	}
+}
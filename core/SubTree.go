@@ -1,18 +1,31 @@
 package core
 
 import (
+	"fmt"
+
 	b3 "github.com/magicsea/behavior3go"
 	. "github.com/magicsea/behavior3go/config"
 )
 
-//子树，通过Name关联树ID查找
+//子树，通过Name关联树ID查找；属性为子树的端口，见SubTreePorts
 type SubTree struct {
 	Action
 	//tree *BehaviorTree
+	ports *SubTreePorts
 }
 
 func (this *SubTree) Initialize(setting *BTNodeCfg) {
 	this.Action.Initialize(setting)
+	var ports, err = ParseSubTreePorts(setting.Properties)
+	if err != nil {
+		panic("SubTree " + setting.Id + ": " + err.Error())
+	}
+	this.ports = ports
+}
+
+//子树的端口，没有时为nil
+func (this *SubTree) GetPorts() *SubTreePorts {
+	return this.ports
 }
 /**
  *执行子树
@@ -22,11 +35,11 @@ func (this *SubTree) Initialize(setting *BTNodeCfg) {
 **/
 func (this *SubTree) OnTick(tick *Tick) b3.Status {
 
-	//使用子树，必须先SetSubTreeLoadFunc
+	//使用子树，必须先SetSubTreeLoadFunc或者树的SetSubTreeLoader
 	//子树可能没有加载上来，所以要延迟加载执行
-	sTree := subTreeLoadFunc(this.GetName())
+	sTree := tick.GetTree().LoadSubTree(this.GetName())
 	if nil == sTree {
-		return b3.ERROR
+		return tick.Fail(this, fmt.Errorf("%w: %s", ErrSubTreeNotFound, this.GetName()))
 	}
 
 	if tick.GetTarget() == nil {
@@ -36,9 +49,23 @@ func (this *SubTree) OnTick(tick *Tick) b3.Status {
 	//tar := tick.GetTarget()
 	//return sTree.Tick(tar, tick.Blackboard)
 
+	//调用方已放弃本次tick，不再进入子树
+	if tick.IsCancelled() {
+		return tick.Fail(this, tick.GetContext().Err())
+	}
+
+	//子树循环引用时在深度限制处停止
+	if max := tick.GetTree().GetMaxSubTreeDepth(); len(tick._openSubtreeNodes) >= max {
+		tick._depthExceeded = true
+		return tick.Fail(this, fmt.Errorf("%w: %d", ErrSubTreeDepth, max))
+	}
+
 	tick.pushSubtreeNode(this)
 	ret := sTree.GetRoot().Execute(tick)
 	tick.popSubtreeNode()
+	if tick.IsCancelled() {
+		return tick.Fail(this, tick.GetContext().Err())
+	}
 	return ret
 }
 
@@ -49,7 +76,55 @@ func (this *SubTree) String() string  {
 
 var subTreeLoadFunc func(string) *BehaviorTree
 
-//获取子树的方法
+//获取子树的方法，树没有自己的SetSubTreeLoader时使用
 func SetSubTreeLoadFunc(f func(string) *BehaviorTree) {
 	subTreeLoadFunc = f
+}
+
+/**
+ * Sets how the SubTree nodes of the tree find their tree, by the name of
+ * the node, instead of the global function of `SetSubTreeLoadFunc`. The
+ * subtrees are ticked with the loader of the ticked tree, so the trees of
+ * a project share one (see `loader.Project`). nil restores the global
+ * function.
+ *
+ * @method SetSubTreeLoader
+ * @param {Function} loader Returns the tree, nil if not found.
+**/
+func (this *BehaviorTree) SetSubTreeLoader(loader func(string) *BehaviorTree) {
+	this.subTreeLoader = loader
+}
+
+//默认的子树最大嵌套深度
+const DEFAULT_MAX_SUBTREE_DEPTH = 64
+
+/**
+ * Sets the maximum number of nested SubTree nodes in a traversal. The
+ * SubTree node which would go deeper fails with `ErrSubTreeDepth`, so a
+ * cycle of subtrees stops instead of overflowing the stack. 0 restores
+ * `DEFAULT_MAX_SUBTREE_DEPTH`.
+**/
+func (this *BehaviorTree) SetMaxSubTreeDepth(depth int) {
+	this.maxSubTreeDepth = depth
+}
+
+func (this *BehaviorTree) GetMaxSubTreeDepth() int {
+	if this.maxSubTreeDepth <= 0 {
+		return DEFAULT_MAX_SUBTREE_DEPTH
+	}
+	return this.maxSubTreeDepth
+}
+
+/**
+ * The tree of a SubTree node, found by the loader of the tree or the
+ * global function. nil if not found.
+**/
+func (this *BehaviorTree) LoadSubTree(name string) *BehaviorTree {
+	if this.subTreeLoader != nil {
+		return this.subTreeLoader(name)
+	}
+	if subTreeLoadFunc != nil {
+		return subTreeLoadFunc(name)
+	}
+	return nil
 }
\ No newline at end of file
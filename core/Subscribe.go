@@ -0,0 +1,141 @@
+package core
+
+/**
+ * A change of a key of the blackboard, given to the subscribers. `Old` is
+ * nil if the key was not set, `New` is nil if it was removed. `Expired` is
+ * set when the key was removed because it expired, see `SetWithTTL`.
+ *
+ * @module b3
+ * @class BlackboardChange
+**/
+type BlackboardChange struct {
+	Key       string
+	TreeScope string
+	NodeScope string
+	Old       interface{}
+	New       interface{}
+	Removed   bool
+	Expired   bool
+}
+
+//订阅，all为true时接收所有作用域的修改
+type blackboardSubscription struct {
+	id        int
+	key       string
+	treeScope string
+	nodeScope string
+	all       bool
+	fn        func(change *BlackboardChange)
+}
+
+func (this *blackboardSubscription) match(change *BlackboardChange) bool {
+	if this.all {
+		return true
+	}
+	if len(this.key) > 0 && this.key != change.Key {
+		return false
+	}
+	return this.treeScope == change.TreeScope && this.nodeScope == change.NodeScope
+}
+
+/**
+ * Calls `fn` every time `key` is set or removed in the given scope, see
+ * `Set` for the scopes. An empty key subscribes to every key of the scope.
+ * Returns the id to give to `Unsubscribe`.
+ *
+ * `fn` is called by the goroutine which wrote the value, right after the
+ * write, in the order of subscription. It can read and write the
+ * blackboard. The global memory changes of the parents are reported too
+ * for the keys not set here, see `SetParent`.
+ *
+ *     var id = blackboard.Subscribe("target", "", "", func(change *b3.BlackboardChange) {
+ *         ui.ShowTarget(change.New)
+ *     })
+ *     defer blackboard.Unsubscribe(id)
+ *
+ * @method Subscribe
+ * @param {String} key The key, empty for all the keys of the scope.
+ * @param {String} treeScope The tree id if watching the tree or node
+ *                           memory.
+ * @param {String} nodeScope The node id if watching the node memory.
+ * @param {Function} fn The function called on change.
+ * @return {Integer} The subscription id.
+**/
+func (this *Blackboard) Subscribe(key, treeScope, nodeScope string, fn func(change *BlackboardChange)) int {
+	if len(treeScope) == 0 {
+		nodeScope = ""
+	}
+	return this._subscribe(&blackboardSubscription{key: key, treeScope: treeScope, nodeScope: nodeScope, fn: fn})
+}
+
+/**
+ * Calls `fn` on every change of the blackboard, in all the scopes.
+**/
+func (this *Blackboard) SubscribeAll(fn func(change *BlackboardChange)) int {
+	return this._subscribe(&blackboardSubscription{all: true, fn: fn})
+}
+
+/**
+ * Removes a subscription. Returns false if it does not exist.
+**/
+func (this *Blackboard) Unsubscribe(id int) bool {
+	this._lock()
+	defer this._unlock()
+	for i, subscription := range this._subscriptions {
+		if subscription.id == id {
+			var subscriptions = make([]*blackboardSubscription, 0, len(this._subscriptions)-1)
+			subscriptions = append(subscriptions, this._subscriptions[:i]...)
+			this._subscriptions = append(subscriptions, this._subscriptions[i+1:]...)
+			this._watchParent()
+			return true
+		}
+	}
+	return false
+}
+
+func (this *Blackboard) _subscribe(subscription *blackboardSubscription) int {
+	this._lock()
+	defer this._unlock()
+	this._subscriptionSeq++
+	subscription.id = this._subscriptionSeq
+	var subscriptions = make([]*blackboardSubscription, 0, len(this._subscriptions)+1)
+	subscriptions = append(subscriptions, this._subscriptions...)
+	this._subscriptions = append(subscriptions, subscription)
+	this._watchParent()
+	return subscription.id
+}
+
+//包内使用的监听所有修改，返回取消函数
+func (this *Blackboard) watch(fn func(change *BlackboardChange)) func() {
+	var id = this.SubscribeAll(fn)
+	return func() {
+		this.Unsubscribe(id)
+	}
+}
+
+func (this *Blackboard) _written(key string, old, value interface{}, removed bool, treeScope, nodeScope string) {
+	if len(treeScope) == 0 {
+		nodeScope = ""
+	}
+	this._notify(&BlackboardChange{Key: key, TreeScope: treeScope, NodeScope: nodeScope, Old: old, New: value, Removed: removed})
+}
+
+//通知订阅，在锁外调用
+func (this *Blackboard) _notify(change *BlackboardChange) {
+	var subscriptions []*blackboardSubscription
+	if this._mutex != nil {
+		this._mutex.RLock()
+		subscriptions = this._subscriptions
+		this._mutex.RUnlock()
+	} else {
+		subscriptions = this._subscriptions
+	}
+	if len(subscriptions) == 0 {
+		return
+	}
+	for _, subscription := range subscriptions {
+		if subscription.match(change) {
+			subscription.fn(change)
+		}
+	}
+}
@@ -1,7 +1,11 @@
 package core
 
 import (
+	"context"
 	_ "fmt"
+	"time"
+
+	b3 "github.com/magicsea/behavior3go"
 )
 
 /**
@@ -41,7 +45,9 @@ type Tick struct {
 	**/
 	target interface{}
 	/**
-	 * The blackboard reference.
+	 * The blackboard reference. It does not translate the keys through the
+	 * ports of the SubTree nodes, the nodes read and write the global
+	 * memory with `GetMem`, `LookupMem` and `SetMem` of the tick.
 	 * @property {b3.Blackboard} blackboard
 	 * @readOnly
 	**/
@@ -58,9 +64,27 @@ type Tick struct {
 	 * The list of open subtree node.
 	 * push subtree node before execute subtree.
 	 * pop subtree node after execute subtree.
+	 * Their ports translate the keys of the global memory, see `PortKey`.
 	**/
 	_openSubtreeNodes []*SubTree
 
+	/**
+	 * The ids of the open SubTree nodes, the prefix of the node scopes. See
+	 * `GetNodeScope`.
+	 * @property {String} _scopePrefix
+	 * @protected
+	**/
+	_scopePrefix string
+
+	/**
+	 * The deepest nesting of SubTree nodes reached, and whether a SubTree
+	 * node was stopped by the depth limit of the tree.
+	 * @property {Integer} _subTreeDepth
+	 * @protected
+	**/
+	_subTreeDepth  int
+	_depthExceeded bool
+
 	/**
 	 * The number of nodes entered during the tick. Update during the tree
 	 * traversal.
@@ -71,6 +95,87 @@ type Tick struct {
 	**/
 	_nodeCount int
 
+	/**
+	 * The context of the tick, set by `BehaviorTree.TickContext`. Nodes can
+	 * watch it to give up long work when the caller cancelled the tick or
+	 * its deadline passed.
+	 * @property {context.Context} ctx
+	 * @readOnly
+	**/
+	ctx context.Context
+
+	/**
+	 * The clock of the tree, the only source of time for the nodes.
+	 * @property {Clock} clock
+	 * @readOnly
+	**/
+	clock Clock
+
+	/**
+	 * The observers notified of the node lifecycle events.
+	 * @property {Array} _observers
+	 * @protected
+	**/
+	_observers []TickObserver
+
+	/**
+	 * The panic policy and handler of the tree when the tick started.
+	 * @property {PanicPolicy} _panic
+	 * @protected
+	**/
+	_panic       PanicPolicy
+	_panicHandler PanicHandler
+
+	/**
+	 * The entered nodes not exited yet, only recorded when observed.
+	 * @property {Array} _frames
+	 * @protected
+	**/
+	_frames []tickFrame
+
+	/**
+	 * The first error attached by a node returning `b3.ERROR`.
+	 * @property {NodeError} _error
+	 * @protected
+	**/
+	_error *NodeError
+
+	/**
+	 * The first write of the current node rejected by the strict schema.
+	 * @property {error} _rejected
+	 * @protected
+	**/
+	_rejected error
+
+	/**
+	 * The driver to wake for the next tick, nil if ticked every frame.
+	 * @property {Waker} waker
+	 * @readOnly
+	**/
+	waker Waker
+
+	/**
+	 * The wall time after which the traversal is suspended, zero for no
+	 * budget. See `TickSliced`.
+	 * @property {time.Time} deadline
+	 * @readOnly
+	**/
+	deadline time.Time
+
+	/**
+	 * Whether the traversal is suspended, and the number of nodes which
+	 * completed before.
+	 * @property {Boolean} _suspended
+	 * @protected
+	**/
+	_suspended bool
+	_completed int
+}
+
+//一个已进入未退出的节点
+type tickFrame struct {
+	enterTime time.Time
+	status    b3.Status
 }
 
 func NewTick() *Tick {
@@ -94,24 +199,71 @@ func (this *Tick) Initialize() {
 	// updated during the tick signal
 	this._openNodes = nil
 	this._openSubtreeNodes = nil
+	this._scopePrefix = ""
+	this._subTreeDepth = 0
+	this._depthExceeded = false
 	this._nodeCount = 0
+	this.ctx = context.Background()
+	this.clock = WallClock{}
+	this._observers = nil
+	this._panic = PANIC_PROPAGATE
+	this._panicHandler = nil
+	this._frames = nil
+	this._error = nil
+	this._rejected = nil
+	this.waker = nil
+	this.deadline = time.Time{}
+	this._suspended = false
+	this._completed = 0
 }
 
 func (this *Tick) GetTree() *BehaviorTree {
 	return this.tree
 }
 
+/**
+ * The context of the tick, never nil.
+**/
+func (this *Tick) GetContext() context.Context {
+	return this.ctx
+}
+
+/**
+ * The clock of the tree. Time-based nodes must read time from it instead
+ * of `time.Now()`.
+**/
+func (this *Tick) GetClock() Clock {
+	return this.clock
+}
+
+/**
+ * Current time of the clock, in milliseconds.
+**/
+func (this *Tick) NowMilli() int64 {
+	return nowMilli(this.clock)
+}
+
+/**
+ * Whether the context of the tick is cancelled or past its deadline.
+**/
+func (this *Tick) IsCancelled() bool {
+	return this.ctx.Err() != nil
+}
+
 /**
  * Called when entering a node (called by BaseNode).
  * @method _enterNode
  * @param {Object} node The node that called this method.
  * @protected
 **/
-func (this *Tick) _enterNode(node IBaseNode) {
+func (this *Tick) _enterNode(node *BaseNode) {
 	this._nodeCount++
-	this._openNodes = append(this._openNodes, node)
+	this._openNodes = append(this._openNodes, this._scopedNode(node))
 
-	// TODO: call debug here
+	if this.isObserved() {
+		this._frames = append(this._frames, tickFrame{enterTime: time.Now()})
+		this._notify(EVENT_ENTER, node, 0, 0)
+	}
 }
 
 /**
@@ -121,18 +273,27 @@ func (this *Tick) _enterNode(node IBaseNode) {
  * @protected
 **/
 func (this *Tick) _openNode(node *BaseNode) {
-	// TODO: call debug here
+	if this.isObserved() {
+		this._notify(EVENT_OPEN, node, 0, 0)
+	}
 }
 
 /**
- * Callback when ticking a node (called by BaseNode).
+ * Callback after ticking a node (called by BaseNode).
  * @method _tickNode
  * @param {Object} node The node that called this method.
+ * @param {Constant} status The status returned by the node.
+ * @param {Duration} elapsed The duration of the tick callback.
  * @protected
 **/
-func (this *Tick) _tickNode(node *BaseNode) {
-	// TODO: call debug here
+func (this *Tick) _tickNode(node *BaseNode, status b3.Status, elapsed time.Duration) {
 	//fmt.Println("Tick _tickNode :", this.debug, " id:", node.GetID(), node.GetTitle())
+	if this.isObserved() {
+		if l := len(this._frames); l > 0 {
+			this._frames[l-1].status = status
+		}
+		this._notify(EVENT_TICK, node, status, elapsed)
+	}
 }
 
 /**
@@ -142,22 +303,47 @@ func (this *Tick) _tickNode(node *BaseNode) {
  * @protected
 **/
 func (this *Tick) _closeNode(node *BaseNode) {
-	// TODO: call debug here
+	if this.isObserved() {
+		this._notify(EVENT_CLOSE, node, 0, 0)
+	}
 
-	ulen := len(this._openNodes)
-	if ulen > 0 {
-		this._openNodes = this._openNodes[:ulen-1]
+	// find the node from the top, nodes opened after it are its children
+	// still running (e.g. a decorator gave up on a running child)
+	var scope = this.GetNodeScope(node)
+	for i := len(this._openNodes) - 1; i >= 0; i-- {
+		if nodeScope(this._openNodes[i]) != scope {
+			continue
+		}
+		var children = append([]IBaseNode{}, this._openNodes[i+1:]...)
+		this._openNodes = this._openNodes[:i]
+		haltNodes(this, children)
+		return
 	}
 
 }
 
+/**
+ * Callback when halting a running node (called by BaseNode).
+ * @method _haltNode
+ * @param {Object} node The node that called this method.
+ * @protected
+**/
+func (this *Tick) _haltNode(node *BaseNode) {
+	if this.isObserved() {
+		this._notify(EVENT_HALT, node, 0, 0)
+	}
+}
+
 func (this *Tick) pushSubtreeNode(node *SubTree)  {
-	this._openSubtreeNodes = append(this._openSubtreeNodes,node)
+	this._setSubTrees(append(this._openSubtreeNodes,node))
+	if len(this._openSubtreeNodes) > this._subTreeDepth {
+		this._subTreeDepth = len(this._openSubtreeNodes)
+	}
 }
 func (this *Tick) popSubtreeNode()  {
 	ulen := len(this._openSubtreeNodes)
 	if ulen>0 {
-		this._openSubtreeNodes = this._openSubtreeNodes[:ulen-1]
+		this._setSubTrees(this._openSubtreeNodes[:ulen-1])
 	}
 }
 
@@ -181,7 +367,106 @@ func (this *Tick) GetLastSubTree() *SubTree {
  * @protected
 **/
 func (this *Tick) _exitNode(node *BaseNode) {
-	// TODO: call debug here
+	if this.isObserved() {
+		var frame tickFrame
+		if l := len(this._frames); l > 0 {
+			frame = this._frames[l-1]
+			this._frames = this._frames[:l-1]
+		}
+		this._notify(EVENT_EXIT, node, frame.status, time.Since(frame.enterTime))
+	}
+}
+
+func (this *Tick) isObserved() bool {
+	return len(this._observers) > 0
+}
+
+//通知所有observer
+func (this *Tick) _notify(typ NodeEventType, node *BaseNode, status b3.Status, elapsed time.Duration) {
+	var event = &NodeEvent{
+		Type:     typ,
+		Tick:     this,
+		Node:     node,
+		ID:       node.GetID(),
+		Scope:    this.GetNodeScope(node),
+		Name:     node.GetName(),
+		Title:    node.GetTitle(),
+		Status:   status,
+		Elapsed:  elapsed,
+		Time:     this.clock.Now(),
+		SubTrees: append([]*SubTree{}, this._openSubtreeNodes...),
+	}
+	for _, observer := range this._observers {
+		observer.OnNodeEvent(event)
+	}
+}
+
+func (this *Tick) _beginTick() {
+	for _, observer := range this._observers {
+		observer.OnTickBegin(this)
+	}
+}
+
+func (this *Tick) _endTick(status b3.Status) {
+	for _, observer := range this._observers {
+		observer.OnTickEnd(this, status)
+	}
+}
+
+/**
+ * The list of SubTree nodes the traversal is currently in, from the
+ * outermost to the innermost. Empty on the major tree.
+**/
+func (this *Tick) GetSubTreeStack() []*SubTree {
+	return append([]*SubTree{}, this._openSubtreeNodes...)
+}
+
+/**
+ * Attaches `err` to the tick as the cause of the failure of `node` and
+ * returns `b3.ERROR`, so a node can fail with:
+ *
+ *     return tick.Fail(this, err)
+ *
+ * Only the first error is kept, it is the deepest one because children
+ * finish before their parents. A node which returns any other status than
+ * `b3.ERROR` drops it, e.g. a Priority going on with its next child, so
+ * the error left at the end of the tick is the cause of the status of the
+ * root. `BehaviorTree.TickE` returns it.
+ *
+ * @method Fail
+ * @param {IBaseNode} node The failing node.
+ * @param {error} err The cause.
+ * @return {Constant} `b3.ERROR`.
+**/
+func (this *Tick) Fail(node IBaseNode, err error) b3.Status {
+	if this._error == nil {
+		if err == nil {
+			err = ErrNodeError
+		}
+		var path = make([]string, 0, len(this._openSubtreeNodes))
+		for _, sub := range this._openSubtreeNodes {
+			path = append(path, sub.GetID())
+		}
+		this._error = &NodeError{
+			NodeID:      node.GetID(),
+			NodeName:    node.GetName(),
+			NodeTitle:   node.GetTitle(),
+			SubTreePath: path,
+			Err:         err,
+		}
+	}
+	return b3.ERROR
+}
+
+/**
+ * The error attached by `Fail`, nil if no node failed or the nodes above
+ * the failing one did not return `b3.ERROR`. It is a `*NodeError`.
+**/
+func (this *Tick) GetError() error {
+	if this._error == nil {
+		return nil
+	}
+	return this._error
 }
 
 func (this *Tick) GetTarget() interface{} {
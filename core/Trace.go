@@ -0,0 +1,376 @@
+package core
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	b3 "github.com/magicsea/behavior3go"
+)
+
+//------------------------Trace-------------------------
+
+//一次节点事件：enter，exit（带状态）或者halt
+type TraceStep struct {
+	Event   string    `json:"event"`
+	NodeID  string    `json:"node"`
+	Name    string    `json:"name,omitempty"`
+	Status  b3.Status `json:"status,omitempty"`
+	SubTree string    `json:"subtree,omitempty"`
+}
+
+//一次黑板写入
+type TraceWrite struct {
+	Key       string     `json:"key"`
+	TreeScope string     `json:"tree,omitempty"`
+	NodeScope string     `json:"node,omitempty"`
+	Value     TypedValue `json:"value"`
+	Removed   bool       `json:"removed,omitempty"`
+}
+
+/**
+ * What happened during one tick of the recorded blackboard.
+ *
+ * - **Inputs** Writes made on the blackboard outside of the tree since the
+ *   previous frame (the first frame holds the whole base memory, with the
+ *   keys read from the parent blackboards).
+ * - **Steps** The visited nodes, in order, with their statuses.
+ * - **Writes** The writes made on the blackboard during the tick.
+ * - **OpenNodes** The scopes of the open nodes at the end of the tick.
+**/
+type TraceFrame struct {
+	Index     int          `json:"index"`
+	Time      time.Time    `json:"time"`
+	Inputs    []TraceWrite `json:"inputs,omitempty"`
+	Steps     []TraceStep  `json:"steps"`
+	Writes    []TraceWrite `json:"writes,omitempty"`
+	Status    b3.Status    `json:"status"`
+	OpenNodes []string     `json:"openNodes"`
+}
+
+/**
+ * The recorded ticks of one tree on one blackboard. It can be saved as
+ * JSON and replayed with `ReplayTrace`.
+ *
+ * @module b3
+ * @class Trace
+**/
+type Trace struct {
+	TreeID   string       `json:"treeId"`
+	ConfigID string       `json:"configId,omitempty"`
+	Title    string       `json:"title,omitempty"`
+	Frames   []TraceFrame `json:"frames"`
+}
+
+//------------------------TraceRecorder-------------------------
+
+/**
+ * The TraceRecorder records every tick of a tree on a single blackboard:
+ * the visited nodes and their statuses, the blackboard writes, and the
+ * open nodes at the end of the tick. Writes made outside of the tree
+ * between two ticks are recorded as inputs of the next frame, so the
+ * trace can be replayed.
+ *
+ * The replay is exact when the recording starts with a blackboard that
+ * holds only base memory (e.g. a new agent). Time read by the nodes is
+ * replayed from the clock time at the beginning of each tick.
+ *
+ *     var recorder = b3.NewTraceRecorder(tree, blackboard)
+ *     ...
+ *     recorder.Stop()
+ *     data, _ := json.Marshal(recorder.GetTrace())
+ *
+ * @module b3
+ * @class TraceRecorder
+**/
+type TraceRecorder struct {
+	BaseTickObserver
+	mutex      sync.Mutex
+	tree       *BehaviorTree
+	blackboard *Blackboard
+	trace      *Trace
+	frame      *TraceFrame
+	inputs     []TraceWrite
+	unwatch    func()
+	err        error
+}
+
+/**
+ * Starts recording the ticks of `tree` on `blackboard`.
+**/
+func NewTraceRecorder(tree *BehaviorTree, blackboard *Blackboard) *TraceRecorder {
+	var recorder = newTraceRecorder(tree, blackboard)
+	recorder.unwatch = blackboard.watch(recorder.onWrite)
+	tree.AddObserver(recorder)
+	return recorder
+}
+
+func newTraceRecorder(tree *BehaviorTree, blackboard *Blackboard) *TraceRecorder {
+	var recorder = &TraceRecorder{tree: tree, blackboard: blackboard}
+	recorder.trace = &Trace{TreeID: tree.GetID(), Title: tree.GetTitile()}
+	if tree.dumpInfo != nil {
+		recorder.trace.ConfigID = tree.dumpInfo.ID
+	}
+	for key, value := range blackboard._resolvedMemory() {
+		recorder.onWrite(&BlackboardChange{Key: key, New: value})
+	}
+	return recorder
+}
+
+//停止记录
+func (this *TraceRecorder) Stop() {
+	this.tree.RemoveObserver(this)
+	this.mutex.Lock()
+	if this.unwatch != nil {
+		this.unwatch()
+		this.unwatch = nil
+	}
+	this.mutex.Unlock()
+}
+
+/**
+ * A copy of the trace recorded so far.
+**/
+func (this *TraceRecorder) GetTrace() *Trace {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	var trace = *this.trace
+	trace.Frames = append([]TraceFrame{}, this.trace.Frames...)
+	return &trace
+}
+
+/**
+ * The first error met while recording, e.g. a value that cannot be
+ * written to JSON.
+**/
+func (this *TraceRecorder) GetError() error {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	return this.err
+}
+
+func (this *TraceRecorder) OnTickBegin(tick *Tick) {
+	if tick.Blackboard != this.blackboard {
+		return
+	}
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	this.frame = &TraceFrame{
+		Index:  len(this.trace.Frames),
+		Time:   tick.GetClock().Now(),
+		Inputs: this.inputs,
+		Steps:  make([]TraceStep, 0),
+	}
+	this.inputs = nil
+}
+
+func (this *TraceRecorder) OnNodeEvent(event *NodeEvent) {
+	if event.Tick.Blackboard != this.blackboard {
+		return
+	}
+	var step = TraceStep{NodeID: event.ID, Name: event.Name, SubTree: subTreePath(event.SubTrees)}
+	switch event.Type {
+	case EVENT_ENTER:
+		step.Event = "enter"
+	case EVENT_EXIT:
+		step.Event = "exit"
+		step.Status = event.Status
+	case EVENT_HALT:
+		step.Event = "halt"
+	default:
+		return
+	}
+
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	if this.frame != nil {
+		this.frame.Steps = append(this.frame.Steps, step)
+	}
+}
+
+func (this *TraceRecorder) OnTickEnd(tick *Tick, status b3.Status) {
+	if tick.Blackboard != this.blackboard {
+		return
+	}
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	if this.frame == nil {
+		return
+	}
+	this.frame.Status = status
+	this.frame.OpenNodes = openNodeScopes(this.blackboard._getTreeData(this.tree.GetID()).OpenNodes)
+	this.trace.Frames = append(this.trace.Frames, *this.frame)
+	this.frame = nil
+}
+
+func (this *TraceRecorder) onWrite(change *BlackboardChange) {
+	typed, err := NewTypedValue(change.New)
+	var write = TraceWrite{Key: change.Key, TreeScope: change.TreeScope, NodeScope: change.NodeScope, Value: typed, Removed: change.Removed}
+
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	if err != nil && this.err == nil {
+		this.err = fmt.Errorf("TraceRecorder: key %s: %v", change.Key, err)
+	}
+	if this.frame != nil {
+		this.frame.Writes = append(this.frame.Writes, write)
+	} else {
+		this.inputs = append(this.inputs, write)
+	}
+}
+
+//子树栈的路径，用/分隔SubTree节点ID
+func subTreePath(subTrees []*SubTree) string {
+	if len(subTrees) == 0 {
+		return ""
+	}
+	var ids = make([]string, 0, len(subTrees))
+	for _, sub := range subTrees {
+		ids = append(ids, sub.GetID())
+	}
+	return strings.Join(ids, "/")
+}
+
+//------------------------Replay-------------------------
+
+/**
+ * Where a replay left the recorded trace. `Step` is -1 when the frame
+ * differs only by its status, its writes or its open nodes.
+**/
+type TraceDivergence struct {
+	Frame    int
+	Step     int
+	Reason   string
+	Expected string
+	Actual   string
+}
+
+func (this *TraceDivergence) String() string {
+	return fmt.Sprintf("frame %d step %d: %s, expected %s, got %s", this.Frame, this.Step, this.Reason, this.Expected, this.Actual)
+}
+
+/**
+ * Re-drives `tree` with the inputs and the clock times of a recorded
+ * trace, on a new blackboard, and compares every frame with the
+ * recording. Returns the first divergence, or nil if the tree behaved
+ * exactly as recorded.
+ *
+ * The clock of the tree is replaced during the replay, so do not replay on
+ * a tree which is ticked at the same time.
+ *
+ * @method ReplayTrace
+ * @param {BehaviorTree} tree The tree to replay, may be a new version.
+ * @param {Trace} trace The recorded trace.
+ * @param {Object} target The target object passed to the ticks.
+ * @return {TraceDivergence} The first divergence or nil.
+**/
+func ReplayTrace(tree *BehaviorTree, trace *Trace, target interface{}) (*TraceDivergence, error) {
+	var clock = NewManualClock(time.Time{})
+	var oldClock = tree.GetClock()
+	tree.SetClock(clock)
+	defer tree.SetClock(oldClock)
+
+	var blackboard = NewBlackboard()
+	var recorder = newTraceRecorder(tree, blackboard)
+	var unwatch = blackboard.watch(recorder.onWrite)
+	defer unwatch()
+
+	// tree scope of the recording is the runtime id of the recorded tree
+	var scope = func(treeScope string) string {
+		if treeScope == trace.TreeID {
+			return tree.GetID()
+		}
+		return treeScope
+	}
+
+	for i := range trace.Frames {
+		var expected = &trace.Frames[i]
+		for _, input := range expected.Inputs {
+			if input.Removed {
+				blackboard._getMemory(scope(input.TreeScope), input.NodeScope).Remove(input.Key)
+				continue
+			}
+			value, err := input.Value.Value()
+			if err != nil {
+				return nil, fmt.Errorf("ReplayTrace: frame %d: %v", expected.Index, err)
+			}
+			blackboard._getMemory(scope(input.TreeScope), input.NodeScope).Set(input.Key, value)
+		}
+		recorder.inputs = nil
+
+		clock.Set(expected.Time)
+		tree.TickWith(target, blackboard, TickOptions{Observers: []TickObserver{recorder}})
+		if err := recorder.GetError(); err != nil {
+			return nil, err
+		}
+
+		var actual = &recorder.trace.Frames[len(recorder.trace.Frames)-1]
+		if divergence := compareFrames(expected, actual, scope); divergence != nil {
+			divergence.Frame = expected.Index
+			return divergence, nil
+		}
+	}
+	return nil, nil
+}
+
+func compareFrames(expected, actual *TraceFrame, scope func(string) string) *TraceDivergence {
+	for i := 0; i < len(expected.Steps) || i < len(actual.Steps); i++ {
+		if i >= len(actual.Steps) {
+			return &TraceDivergence{Step: i, Reason: "missing step", Expected: formatStep(expected.Steps[i]), Actual: "nothing"}
+		}
+		if i >= len(expected.Steps) {
+			return &TraceDivergence{Step: i, Reason: "extra step", Expected: "nothing", Actual: formatStep(actual.Steps[i])}
+		}
+		if expected.Steps[i] != actual.Steps[i] {
+			return &TraceDivergence{Step: i, Reason: "different step", Expected: formatStep(expected.Steps[i]), Actual: formatStep(actual.Steps[i])}
+		}
+	}
+
+	if expected.Status != actual.Status {
+		return &TraceDivergence{Step: -1, Reason: "different status", Expected: fmt.Sprint(expected.Status), Actual: fmt.Sprint(actual.Status)}
+	}
+
+	for i := 0; i < len(expected.Writes) || i < len(actual.Writes); i++ {
+		if i >= len(actual.Writes) {
+			return &TraceDivergence{Step: -1, Reason: "missing write", Expected: formatWrite(expected.Writes[i]), Actual: "nothing"}
+		}
+		if i >= len(expected.Writes) {
+			return &TraceDivergence{Step: -1, Reason: "extra write", Expected: "nothing", Actual: formatWrite(actual.Writes[i])}
+		}
+		var e, a = expected.Writes[i], actual.Writes[i]
+		e.TreeScope = scope(e.TreeScope)
+		if e.Key != a.Key || e.TreeScope != a.TreeScope || e.NodeScope != a.NodeScope || e.Removed != a.Removed ||
+			e.Value.Type != a.Value.Type || !bytes.Equal(e.Value.Data, a.Value.Data) {
+			return &TraceDivergence{Step: -1, Reason: "different write", Expected: formatWrite(e), Actual: formatWrite(a)}
+		}
+	}
+
+	var e, a = strings.Join(expected.OpenNodes, ","), strings.Join(actual.OpenNodes, ",")
+	if e != a {
+		return &TraceDivergence{Step: -1, Reason: "different open nodes", Expected: "[" + e + "]", Actual: "[" + a + "]"}
+	}
+	return nil
+}
+
+func formatStep(step TraceStep) string {
+	var s = step.Event + " " + step.NodeID
+	if len(step.Name) > 0 {
+		s += "(" + step.Name + ")"
+	}
+	if step.Event == "exit" {
+		s += fmt.Sprintf(" status %d", step.Status)
+	}
+	if len(step.SubTree) > 0 {
+		s += " in " + step.SubTree
+	}
+	return s
+}
+
+func formatWrite(write TraceWrite) string {
+	if write.Removed {
+		return fmt.Sprintf("remove %s [%s/%s]", write.Key, write.TreeScope, write.NodeScope)
+	}
+	return fmt.Sprintf("set %s [%s/%s] = %s %s", write.Key, write.TreeScope, write.NodeScope, write.Value.Type, string(write.Value.Data))
+}
@@ -0,0 +1,90 @@
+package core_test
+
+import (
+	"testing"
+	"time"
+
+	b3 "github.com/magicsea/behavior3go"
+	. "github.com/magicsea/behavior3go/config"
+	. "github.com/magicsea/behavior3go/core"
+)
+
+//先成功一个节点再等待，时钟从Unix 100开始
+func createWaitTree(milliseconds float64) *BehaviorTree {
+	var tree = createTestTree("patrol", "seq",
+		BTNodeCfg{Id: "seq", Name: "MemSequence", Category: b3.COMPOSITE, Children: []string{"ok", "wait"}},
+		BTNodeCfg{Id: "ok", Name: "Succeeder", Category: b3.ACTION},
+		BTNodeCfg{Id: "wait", Name: "Wait", Category: b3.ACTION, Properties: map[string]interface{}{"milliseconds": milliseconds}},
+	)
+	tree.SetClock(NewManualClock(time.Unix(100, 0)))
+	return tree
+}
+
+func TestTraceReplay(t *testing.T) {
+	var tree = createWaitTree(1000)
+	var clock = tree.GetClock().(*ManualClock)
+	var blackboard = NewBlackboard()
+	var recorder = NewTraceRecorder(tree, blackboard)
+	for i := 0; i < 4; i++ {
+		blackboard.SetMem("frame", i)
+		tree.Tick(nil, blackboard)
+		clock.Advance(600 * time.Millisecond)
+	}
+	recorder.Stop()
+	if err := recorder.GetError(); err != nil {
+		t.Fatal(err)
+	}
+	var trace = recorder.GetTrace()
+	if len(trace.Frames) != 4 {
+		t.Fatal("frames:", len(trace.Frames))
+	}
+
+	var replayed = createWaitTree(1000)
+	var replayClock = replayed.GetClock()
+	divergence, err := ReplayTrace(replayed, trace, nil)
+	if err != nil || divergence != nil {
+		t.Error("replay:", divergence, err)
+	}
+	if replayed.GetClock() != replayClock {
+		t.Error("tree clock not restored")
+	}
+
+	// a tree waiting longer runs differently
+	var changed = createWaitTree(5000)
+	changed.SetClock(nil)
+	divergence, err = ReplayTrace(changed, trace, nil)
+	if err != nil || divergence == nil {
+		t.Error("divergence not found:", err)
+	}
+	if _, ok := changed.GetClock().(WallClock); !ok {
+		t.Error("tree clock not restored:", changed.GetClock())
+	}
+}
+
+func TestTraceRecordParent(t *testing.T) {
+	var tree = createWaitTree(1000)
+	var world = NewSyncBlackboard()
+	world.SetMem("night", true)
+	world.SetMem("alarm", 1)
+	var blackboard = NewBlackboard()
+	blackboard.SetParent(world)
+	blackboard.SetMem("alarm", 2)
+
+	var recorder = NewTraceRecorder(tree, blackboard)
+	tree.Tick(nil, blackboard)
+	world.SetMem("night", false)
+	tree.Tick(nil, blackboard)
+	recorder.Stop()
+
+	var frames = recorder.GetTrace().Frames
+	var inputs = make(map[string]interface{})
+	for _, input := range frames[0].Inputs {
+		inputs[input.Key], _ = input.Value.Value()
+	}
+	if inputs["night"] != true || inputs["alarm"] != 2 {
+		t.Error("first inputs:", inputs)
+	}
+	if len(frames[1].Inputs) != 1 || frames[1].Inputs[0].Key != "night" {
+		t.Error("second inputs:", frames[1].Inputs)
+	}
+}
@@ -0,0 +1,133 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+/**
+ * A blackboard value with its Go type name, so it can be written to JSON
+ * and read back with the same type (a plain JSON round trip turns every
+ * number into `float64`).
+ *
+ * Basic types (bool, string, all int/uint/float kinds) are restored as is.
+ * Other types are restored as the type registered with `RegisterValueType`,
+ * or as the generic JSON value if the type is unknown.
+ *
+ * @module b3
+ * @class TypedValue
+**/
+type TypedValue struct {
+	Type string          `json:"type"`
+	Data json.RawMessage `json:"data,omitempty"`
+}
+
+var valueTypes = struct {
+	sync.RWMutex
+	types map[string]reflect.Type
+}{types: make(map[string]reflect.Type)}
+
+/**
+ * Registers the type of `sample` so that `TypedValue` restores values of
+ * this type as the type itself instead of a generic JSON value.
+**/
+func RegisterValueType(sample interface{}) {
+	var t = reflect.TypeOf(sample)
+	valueTypes.Lock()
+	valueTypes.types[t.String()] = t
+	valueTypes.Unlock()
+}
+
+func NewTypedValue(value interface{}) (TypedValue, error) {
+	if value == nil {
+		return TypedValue{Type: "nil"}, nil
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return TypedValue{}, fmt.Errorf("TypedValue: %v", err)
+	}
+	return TypedValue{Type: reflect.TypeOf(value).String(), Data: data}, nil
+}
+
+//还原为原来类型的值
+func (this TypedValue) Value() (interface{}, error) {
+	var value interface{}
+	var err error
+	switch this.Type {
+	case "nil", "":
+		return nil, nil
+	case "bool":
+		var v bool
+		err = json.Unmarshal(this.Data, &v)
+		value = v
+	case "string":
+		var v string
+		err = json.Unmarshal(this.Data, &v)
+		value = v
+	case "int":
+		var v int
+		err = json.Unmarshal(this.Data, &v)
+		value = v
+	case "int8":
+		var v int8
+		err = json.Unmarshal(this.Data, &v)
+		value = v
+	case "int16":
+		var v int16
+		err = json.Unmarshal(this.Data, &v)
+		value = v
+	case "int32":
+		var v int32
+		err = json.Unmarshal(this.Data, &v)
+		value = v
+	case "int64":
+		var v int64
+		err = json.Unmarshal(this.Data, &v)
+		value = v
+	case "uint":
+		var v uint
+		err = json.Unmarshal(this.Data, &v)
+		value = v
+	case "uint8":
+		var v uint8
+		err = json.Unmarshal(this.Data, &v)
+		value = v
+	case "uint16":
+		var v uint16
+		err = json.Unmarshal(this.Data, &v)
+		value = v
+	case "uint32":
+		var v uint32
+		err = json.Unmarshal(this.Data, &v)
+		value = v
+	case "uint64":
+		var v uint64
+		err = json.Unmarshal(this.Data, &v)
+		value = v
+	case "float32":
+		var v float32
+		err = json.Unmarshal(this.Data, &v)
+		value = v
+	case "float64":
+		var v float64
+		err = json.Unmarshal(this.Data, &v)
+		value = v
+	default:
+		valueTypes.RLock()
+		t, ok := valueTypes.types[this.Type]
+		valueTypes.RUnlock()
+		if ok {
+			var ptr = reflect.New(t)
+			err = json.Unmarshal(this.Data, ptr.Interface())
+			value = ptr.Elem().Interface()
+		} else {
+			err = json.Unmarshal(this.Data, &value)
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("TypedValue %s: %v", this.Type, err)
+	}
+	return value, nil
+}
@@ -0,0 +1,208 @@
+package core_test
+
+import (
+	"errors"
+	"time"
+
+	b3 "github.com/magicsea/behavior3go"
+	. "github.com/magicsea/behavior3go/config"
+	. "github.com/magicsea/behavior3go/core"
+	. "github.com/magicsea/behavior3go/loader"
+)
+
+//每次tick计数，sleep用于模拟耗时的节点，设置了clock时在clock上经过
+type npc struct {
+	ticks int
+	sleep time.Duration
+	clock *ManualClock
+}
+
+type CountAction struct {
+	Action
+}
+
+func (this *CountAction) OnTick(tick *Tick) b3.Status {
+	var target = tick.GetTarget().(*npc)
+	target.ticks++
+	if target.clock != nil {
+		target.clock.Advance(target.sleep)
+	} else {
+		time.Sleep(target.sleep)
+	}
+	tick.SetMem("ticks", target.ticks)
+	return b3.RUNNING
+}
+
+//每个节点的tick次数
+type sliceTarget map[string]int
+
+type SlowAction struct {
+	Action
+}
+
+func (this *SlowAction) OnTick(tick *Tick) b3.Status {
+	tick.GetTarget().(sliceTarget)[this.GetID()]++
+	time.Sleep(2 * time.Millisecond)
+	return b3.SUCCESS
+}
+
+//写入属性key指定的键，设置了ttl（毫秒）时按树的时钟过期
+type SetKey struct {
+	Action
+	key string
+	ttl time.Duration
+}
+
+func (this *SetKey) Initialize(setting *BTNodeCfg) {
+	this.Action.Initialize(setting)
+	this.key = setting.GetPropertyAsString("key")
+	if _, ok := setting.Properties["ttl"]; ok {
+		this.ttl = time.Duration(setting.GetPropertyAsInt64("ttl")) * time.Millisecond
+	}
+}
+
+func (this *SetKey) OnTick(tick *Tick) b3.Status {
+	if this.ttl > 0 {
+		tick.SetMemWithTTL(this.key, 1, this.ttl)
+	} else {
+		tick.SetMem(this.key, 1)
+	}
+	return b3.SUCCESS
+}
+
+//记录节点回调的顺序，格式为"节点ID:回调"
+type callLog []string
+
+func logCall(tick *Tick, id, call string) {
+	if log, ok := tick.GetTarget().(*callLog); ok {
+		*log = append(*log, id+":"+call)
+	}
+}
+
+//一直运行的节点，记录open/halt/close
+type RunAction struct {
+	Action
+}
+
+func (this *RunAction) OnOpen(tick *Tick) {
+	logCall(tick, this.GetID(), "open")
+}
+
+func (this *RunAction) OnTick(tick *Tick) b3.Status {
+	return b3.RUNNING
+}
+
+func (this *RunAction) OnHalt(tick *Tick) {
+	logCall(tick, this.GetID(), "halt")
+}
+
+func (this *RunAction) OnClose(tick *Tick) {
+	logCall(tick, this.GetID(), "close")
+}
+
+var errBoom = errors.New("boom")
+
+//以errBoom失败的节点
+type FailAction struct {
+	Action
+}
+
+func (this *FailAction) OnTick(tick *Tick) b3.Status {
+	return tick.Fail(this, errBoom)
+}
+
+//执行完子节点后panic
+type PanicComposite struct {
+	Composite
+}
+
+func (this *PanicComposite) OnTick(tick *Tick) b3.Status {
+	for i := 0; i < this.GetChildCount(); i++ {
+		this.GetChild(i).Execute(tick)
+	}
+	panic("boom")
+}
+
+//调用target函数的节点
+type CallAction struct {
+	Action
+}
+
+func (this *CallAction) OnTick(tick *Tick) b3.Status {
+	tick.GetTarget().(func())()
+	return b3.SUCCESS
+}
+
+//通过子树端口读取target，写入result
+type CopyTarget struct {
+	Action
+}
+
+func (this *CopyTarget) OnTick(tick *Tick) b3.Status {
+	var target, ok = tick.LookupMem("target")
+	if !ok {
+		return b3.FAILURE
+	}
+	tick.SetMem("result", target)
+	tick.SetMem("target", nil)
+	return b3.SUCCESS
+}
+
+//注册了所有测试节点的结构表
+func testStructMaps() *b3.RegisterStructMaps {
+	var maps = b3.NewRegisterStructMaps()
+	maps.Register("CountAction", new(CountAction))
+	maps.Register("SlowAction", new(SlowAction))
+	maps.Register("SetKey", new(SetKey))
+	maps.Register("CopyTarget", new(CopyTarget))
+	maps.Register("SquadAction", new(SquadAction))
+	maps.Register("RunAction", new(RunAction))
+	maps.Register("FailAction", new(FailAction))
+	maps.Register("PanicComposite", new(PanicComposite))
+	maps.Register("CallAction", new(CallAction))
+	return maps
+}
+
+//由节点列表构造树配置
+func treeConfig(id, root string, nodes ...BTNodeCfg) *BTTreeCfg {
+	var config = &BTTreeCfg{
+		ID:    id,
+		Title: id,
+		Root:  root,
+		Nodes: make(map[string]BTNodeCfg, len(nodes)),
+	}
+	for _, node := range nodes {
+		config.Nodes[node.Id] = node
+	}
+	return config
+}
+
+//由节点列表创建测试树
+func createTestTree(id, root string, nodes ...BTNodeCfg) *BehaviorTree {
+	return CreateBevTreeFromConfig(treeConfig(id, root, nodes...), testStructMaps())
+}
+
+//按名字加载给定的子树，返回的函数用于恢复
+func useSubTrees(trees ...*BehaviorTree) func() {
+	var byID = make(map[string]*BehaviorTree, len(trees))
+	for _, tree := range trees {
+		byID[tree.GetTitile()] = tree
+	}
+	SetSubTreeLoadFunc(func(id string) *BehaviorTree {
+		return byID[id]
+	})
+	return func() { SetSubTreeLoadFunc(nil) }
+}
+
+//引用子树的SubTree节点
+func subTreeNode(id, subTree string, properties map[string]interface{}) BTNodeCfg {
+	return BTNodeCfg{Id: id, Name: subTree, Category: "tree", Properties: properties}
+}
+
+func createCountTree() *BehaviorTree {
+	return createTestTree("count", "seq",
+		BTNodeCfg{Id: "seq", Name: "MemSequence", Category: b3.COMPOSITE, Children: []string{"ok", "count"}},
+		BTNodeCfg{Id: "ok", Name: "Succeeder", Category: b3.ACTION},
+		BTNodeCfg{Id: "count", Name: "CountAction", Category: b3.ACTION},
+	)
+}
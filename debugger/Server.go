@@ -0,0 +1,422 @@
+package debugger
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net"
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	b3 "github.com/magicsea/behavior3go"
+	. "github.com/magicsea/behavior3go/core"
+)
+
+/**
+ * Server is a live debugger for the trees of a running process. Clients
+ * connect with plain TCP and talk JSON, one message per line. The node IDs
+ * in the messages are the IDs of the editor, so a client can highlight the
+ * running nodes on the tree opened in behavior3editor. Inside a subtree the
+ * ID is prefixed by the SubTree nodes, `<subtree node id>/<id>` (see
+ * `Tick.GetNodeScope`), so every reference of the subtree is shown apart.
+ *
+ * Register the agents that can be debugged with `AddAgent`, and the server
+ * on the trees with `tree.AddObserver(server)`. Only the selected agent is
+ * streamed, after each of its ticks:
+ *
+ *     {"type":"tick","agent":"npc1","tree":"<tree id>","title":"...",
+ *      "status":3,"path":["<id>",...],"nodes":{"<id>":1,...}}
+ *
+ * `path` is the list of running nodes from the root, `nodes` the status
+ * returned by every node visited during the tick.
+ *
+ * Commands of the client:
+ *
+ *     {"cmd":"agents"}                       list the agents
+ *     {"cmd":"select","agent":"npc1"}        stream this agent
+ *     {"cmd":"pause"}                        pause before the next tick
+ *     {"cmd":"resume"}                       resume
+ *     {"cmd":"step"}                         run one tick, then pause again
+ *     {"cmd":"break","node":"<id>"}          pause when entering the node,
+ *                                            a plain ID breaks in every subtree
+ *     {"cmd":"unbreak","node":"<id>"}        remove a breakpoint
+ *     {"cmd":"clear"}                        remove all breakpoints
+ *
+ * Pausing blocks the goroutine which ticks the selected agent, until the
+ * client resumes or steps, the last client disconnects or the server is
+ * closed. While no client is connected, or for the agents which are not
+ * selected, the events return without taking the lock of the server.
+ *
+ * @module b3
+ * @class Server
+**/
+type Server struct {
+	BaseTickObserver
+	mutex    sync.Mutex
+	resumed  *sync.Cond
+	listener net.Listener
+	clients  map[*client]bool
+	closed   bool
+
+	agents      map[string]*Blackboard
+	selected    string
+	paused      bool
+	step        bool
+	breakpoints map[string]bool
+
+	//选中agent当前tick的记录
+	visited  []string
+	statuses map[string]b3.Status
+	//有客户端时选中agent的黑板，事件不加锁先检查
+	watched atomic.Pointer[Blackboard]
+}
+
+//服务器发送的消息
+type Message struct {
+	Type        string               `json:"type"`
+	Agent       string               `json:"agent,omitempty"`
+	Agents      []string             `json:"agents,omitempty"`
+	Tree        string               `json:"tree,omitempty"`
+	Title       string               `json:"title,omitempty"`
+	Status      b3.Status            `json:"status,omitempty"`
+	Path        []string             `json:"path,omitempty"`
+	Nodes       map[string]b3.Status `json:"nodes,omitempty"`
+	Node        string               `json:"node,omitempty"`
+	Reason      string               `json:"reason,omitempty"`
+	Breakpoints []string             `json:"breakpoints,omitempty"`
+}
+
+//客户端发送的命令
+type Command struct {
+	Cmd   string `json:"cmd"`
+	Agent string `json:"agent,omitempty"`
+	Node  string `json:"node,omitempty"`
+}
+
+type client struct {
+	conn net.Conn
+	send chan []byte
+}
+
+func NewServer() *Server {
+	var server = &Server{
+		clients:     make(map[*client]bool),
+		agents:      make(map[string]*Blackboard),
+		breakpoints: make(map[string]bool),
+	}
+	server.resumed = sync.NewCond(&server.mutex)
+	return server
+}
+
+/**
+ * Listens on the TCP address, e.g. "127.0.0.1:9527", and serves the
+ * clients in the background.
+**/
+func (this *Server) Listen(addr string) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	this.mutex.Lock()
+	this.listener = listener
+	this.mutex.Unlock()
+	go this.accept(listener)
+	return nil
+}
+
+func (this *Server) Addr() net.Addr {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	if this.listener == nil {
+		return nil
+	}
+	return this.listener.Addr()
+}
+
+/**
+ * Stops the server, disconnects the clients and resumes the paused agent.
+**/
+func (this *Server) Close() error {
+	this.mutex.Lock()
+	this.closed = true
+	this.paused = false
+	this.updateWatched()
+	var listener = this.listener
+	for c := range this.clients {
+		c.conn.Close()
+	}
+	this.resumed.Broadcast()
+	this.mutex.Unlock()
+
+	if listener != nil {
+		return listener.Close()
+	}
+	return nil
+}
+
+//注册可调试的agent
+func (this *Server) AddAgent(name string, blackboard *Blackboard) {
+	this.mutex.Lock()
+	this.agents[name] = blackboard
+	this.updateWatched()
+	this.mutex.Unlock()
+}
+
+func (this *Server) RemoveAgent(name string) {
+	this.mutex.Lock()
+	delete(this.agents, name)
+	if this.selected == name {
+		this.selected = ""
+		this.paused = false
+		this.resumed.Broadcast()
+	}
+	this.updateWatched()
+	this.mutex.Unlock()
+}
+
+//选中调试的agent
+func (this *Server) Select(name string) {
+	this.mutex.Lock()
+	this.selected = name
+	this.paused = false
+	this.resumed.Broadcast()
+	this.updateWatched()
+	this.mutex.Unlock()
+}
+
+//------------------------TickObserver-------------------------
+
+func (this *Server) OnTickBegin(tick *Tick) {
+	if this.watched.Load() != tick.Blackboard {
+		return
+	}
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	if !this.isSelected(tick) {
+		return
+	}
+	this.visited = nil
+	this.statuses = make(map[string]b3.Status)
+	this.waitResumed()
+}
+
+func (this *Server) OnNodeEvent(event *NodeEvent) {
+	if event.Type != EVENT_ENTER && event.Type != EVENT_EXIT || this.watched.Load() != event.Tick.Blackboard {
+		return
+	}
+
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	if !this.isSelected(event.Tick) || this.statuses == nil {
+		return
+	}
+	if event.Type == EVENT_EXIT {
+		this.statuses[event.Scope] = event.Status
+		return
+	}
+
+	this.visited = append(this.visited, event.Scope)
+	if len(this.clients) > 0 && (this.breakpoints[event.Scope] || this.breakpoints[event.ID]) {
+		this.paused = true
+		this.step = false
+		this.broadcast(&Message{Type: "paused", Agent: this.selected, Node: event.Scope, Reason: "breakpoint"})
+		this.waitResumed()
+	}
+}
+
+func (this *Server) OnTickEnd(tick *Tick, status b3.Status) {
+	if this.watched.Load() != tick.Blackboard {
+		return
+	}
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	if !this.isSelected(tick) || this.statuses == nil {
+		return
+	}
+
+	var path = make([]string, 0)
+	for _, id := range this.visited {
+		if this.statuses[id] == b3.RUNNING {
+			path = append(path, id)
+		}
+	}
+	this.broadcast(&Message{
+		Type:   "tick",
+		Agent:  this.selected,
+		Tree:   tick.GetTree().GetID(),
+		Title:  tick.GetTree().GetTitile(),
+		Status: status,
+		Path:   path,
+		Nodes:  this.statuses,
+	})
+	this.visited = nil
+	this.statuses = nil
+}
+
+//必须持有锁
+func (this *Server) isSelected(tick *Tick) bool {
+	return len(this.selected) > 0 && this.agents[this.selected] == tick.Blackboard
+}
+
+//更新不加锁检查的黑板，没有客户端或者没有选中时不记录tick，必须持有锁
+func (this *Server) updateWatched() {
+	var blackboard = this.agents[this.selected]
+	if len(this.selected) == 0 || len(this.clients) == 0 || this.closed {
+		blackboard = nil
+	}
+	if blackboard == nil {
+		this.visited = nil
+		this.statuses = nil
+	}
+	this.watched.Store(blackboard)
+}
+
+//暂停时阻塞，直到继续或者单步，没有客户端时不再暂停，必须持有锁
+func (this *Server) waitResumed() {
+	for this.paused && !this.step && !this.closed && len(this.clients) > 0 {
+		this.resumed.Wait()
+	}
+	this.step = false
+}
+
+//------------------------clients-------------------------
+
+func (this *Server) accept(listener net.Listener) {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		var c = &client{conn: conn, send: make(chan []byte, 64)}
+		this.mutex.Lock()
+		if this.closed {
+			this.mutex.Unlock()
+			conn.Close()
+			return
+		}
+		this.clients[c] = true
+		this.updateWatched()
+		this.sendTo(c, this.agentsMessage())
+		this.mutex.Unlock()
+
+		go this.write(c)
+		go this.read(c)
+	}
+}
+
+func (this *Server) write(c *client) {
+	for data := range c.send {
+		if _, err := c.conn.Write(data); err != nil {
+			c.conn.Close()
+			return
+		}
+	}
+}
+
+func (this *Server) read(c *client) {
+	var scanner = bufio.NewScanner(c.conn)
+	for scanner.Scan() {
+		var cmd Command
+		if err := json.Unmarshal(scanner.Bytes(), &cmd); err != nil {
+			this.mutex.Lock()
+			this.sendTo(c, &Message{Type: "error", Reason: fmt.Sprintf("bad command: %v", err)})
+			this.mutex.Unlock()
+			continue
+		}
+		this.handle(c, &cmd)
+	}
+
+	this.mutex.Lock()
+	delete(this.clients, c)
+	close(c.send)
+	// nobody left to resume the agent
+	if len(this.clients) == 0 {
+		this.paused = false
+		this.step = false
+		this.resumed.Broadcast()
+	}
+	this.updateWatched()
+	this.mutex.Unlock()
+	c.conn.Close()
+}
+
+func (this *Server) handle(c *client, cmd *Command) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+
+	switch cmd.Cmd {
+	case "agents":
+		this.sendTo(c, this.agentsMessage())
+	case "select":
+		if _, ok := this.agents[cmd.Agent]; !ok {
+			this.sendTo(c, &Message{Type: "error", Reason: "unknown agent " + cmd.Agent})
+			return
+		}
+		this.selected = cmd.Agent
+		this.paused = false
+		this.resumed.Broadcast()
+		this.updateWatched()
+		this.broadcast(&Message{Type: "selected", Agent: cmd.Agent})
+	case "pause":
+		this.paused = true
+		this.broadcast(&Message{Type: "paused", Agent: this.selected, Reason: "pause"})
+	case "resume":
+		this.paused = false
+		this.resumed.Broadcast()
+		this.broadcast(&Message{Type: "resumed", Agent: this.selected})
+	case "step":
+		this.paused = true
+		this.step = true
+		this.resumed.Broadcast()
+	case "break":
+		this.breakpoints[cmd.Node] = true
+		this.broadcast(this.breakpointsMessage())
+	case "unbreak":
+		delete(this.breakpoints, cmd.Node)
+		this.broadcast(this.breakpointsMessage())
+	case "clear":
+		this.breakpoints = make(map[string]bool)
+		this.broadcast(this.breakpointsMessage())
+	default:
+		this.sendTo(c, &Message{Type: "error", Reason: "unknown command " + cmd.Cmd})
+	}
+}
+
+func (this *Server) agentsMessage() *Message {
+	var names = make([]string, 0, len(this.agents))
+	for name := range this.agents {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return &Message{Type: "agents", Agents: names, Agent: this.selected}
+}
+
+func (this *Server) breakpointsMessage() *Message {
+	var ids = make([]string, 0, len(this.breakpoints))
+	for id := range this.breakpoints {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return &Message{Type: "breakpoints", Breakpoints: ids}
+}
+
+//必须持有锁
+func (this *Server) broadcast(msg *Message) {
+	for c := range this.clients {
+		this.sendTo(c, msg)
+	}
+}
+
+//必须持有锁，客户端太慢时丢弃消息，不阻塞tick
+func (this *Server) sendTo(c *client, msg *Message) {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return
+	}
+	data = append(data, '\n')
+	select {
+	case c.send <- data:
+	default:
+	}
+}
@@ -0,0 +1,177 @@
+package debugger_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"net"
+	"testing"
+	"time"
+
+	b3 "github.com/magicsea/behavior3go"
+	. "github.com/magicsea/behavior3go/config"
+	. "github.com/magicsea/behavior3go/core"
+	. "github.com/magicsea/behavior3go/debugger"
+	. "github.com/magicsea/behavior3go/loader"
+)
+
+//一直运行的树
+func createDebugTree() *BehaviorTree {
+	return CreateBevTreeFromConfig(&BTTreeCfg{ID: "main", Title: "main", Root: "seq", Nodes: map[string]BTNodeCfg{
+		"seq": {Id: "seq", Name: "Sequence", Category: b3.COMPOSITE, Children: []string{"run"}},
+		"run": {Id: "run", Name: "Runner", Category: b3.ACTION},
+	}}, nil)
+}
+
+//主树用SubTree节点job引用一直运行的子树，返回恢复子树加载的函数
+func createSubTreeDebugTree() (*BehaviorTree, func()) {
+	var child = CreateBevTreeFromConfig(&BTTreeCfg{ID: "child", Title: "child", Root: "run", Nodes: map[string]BTNodeCfg{
+		"run": {Id: "run", Name: "Runner", Category: b3.ACTION},
+	}}, nil)
+	SetSubTreeLoadFunc(func(id string) *BehaviorTree {
+		if id == "child" {
+			return child
+		}
+		return nil
+	})
+	var tree = CreateBevTreeFromConfig(&BTTreeCfg{ID: "main", Title: "main", Root: "job", Nodes: map[string]BTNodeCfg{
+		"job": {Id: "job", Name: "child", Category: "tree"},
+	}}, nil)
+	return tree, func() { SetSubTreeLoadFunc(nil) }
+}
+
+type debugClient struct {
+	t       *testing.T
+	conn    net.Conn
+	scanner *bufio.Scanner
+}
+
+func dial(t *testing.T, server *Server) *debugClient {
+	conn, err := net.Dial("tcp", server.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var c = &debugClient{t: t, conn: conn, scanner: bufio.NewScanner(conn)}
+	c.expect("agents")
+	return c
+}
+
+func (this *debugClient) send(cmd Command) {
+	data, _ := json.Marshal(cmd)
+	if _, err := this.conn.Write(append(data, '\n')); err != nil {
+		this.t.Fatal(err)
+	}
+}
+
+//读取消息，直到指定类型
+func (this *debugClient) expect(msgType string) *Message {
+	this.conn.SetReadDeadline(time.Now().Add(time.Second))
+	for this.scanner.Scan() {
+		var msg Message
+		if err := json.Unmarshal(this.scanner.Bytes(), &msg); err != nil {
+			this.t.Fatal(err)
+		}
+		if msg.Type == msgType {
+			return &msg
+		}
+	}
+	this.t.Fatal("no message", msgType, this.scanner.Err())
+	return nil
+}
+
+func TestServerTick(t *testing.T) {
+	var server = NewServer()
+	if err := server.Listen("127.0.0.1:0"); err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+	var tree = createDebugTree()
+	tree.AddObserver(server)
+	var blackboard = NewBlackboard()
+	server.AddAgent("npc", blackboard)
+
+	var client = dial(t, server)
+	defer client.conn.Close()
+	client.send(Command{Cmd: "select", Agent: "npc"})
+	client.expect("selected")
+
+	tree.Tick("npc", blackboard)
+	var msg = client.expect("tick")
+	if msg.Nodes["run"] != b3.RUNNING || len(msg.Path) != 2 || msg.Path[1] != "run" {
+		t.Error("nodes", msg.Nodes, "path", msg.Path)
+	}
+}
+
+func TestServerNodeScopes(t *testing.T) {
+	var server = NewServer()
+	if err := server.Listen("127.0.0.1:0"); err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+	tree, restore := createSubTreeDebugTree()
+	defer restore()
+	tree.AddObserver(server)
+	var blackboard = NewBlackboard()
+	server.AddAgent("npc", blackboard)
+
+	var client = dial(t, server)
+	defer client.conn.Close()
+	client.send(Command{Cmd: "select", Agent: "npc"})
+	client.expect("selected")
+
+	tree.Tick("npc", blackboard)
+	var msg = client.expect("tick")
+	if msg.Nodes["job/run"] != b3.RUNNING || len(msg.Path) != 2 || msg.Path[1] != "job/run" {
+		t.Error("nodes", msg.Nodes, "path", msg.Path)
+	}
+}
+
+func TestServerResumeOnDisconnect(t *testing.T) {
+	var server = NewServer()
+	if err := server.Listen("127.0.0.1:0"); err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+	var tree = createDebugTree()
+	tree.AddObserver(server)
+	var blackboard = NewBlackboard()
+	server.AddAgent("npc", blackboard)
+
+	var client = dial(t, server)
+	client.send(Command{Cmd: "select", Agent: "npc"})
+	client.expect("selected")
+	client.send(Command{Cmd: "pause"})
+	client.expect("paused")
+
+	var done = make(chan b3.Status)
+	go func() {
+		done <- tree.Tick("npc", blackboard)
+	}()
+	select {
+	case <-done:
+		t.Fatal("tick not paused")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// nobody can resume the agent any more
+	client.conn.Close()
+	select {
+	case status := <-done:
+		if status != b3.RUNNING {
+			t.Error("status", status)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("tick still paused")
+	}
+
+	// a new client finds the agent running
+	client = dial(t, server)
+	defer client.conn.Close()
+	go func() {
+		done <- tree.Tick("npc", blackboard)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("paused again")
+	}
+}
@@ -48,16 +48,25 @@ func (this *Limiter) Initialize(setting *BTNodeCfg) {
 **/
 func (this *Limiter) OnTick(tick *Tick) b3.Status {
 	if this.GetChild() == nil {
-		return b3.ERROR
+		return tick.Fail(this, ErrNoChild)
 	}
-	var i = tick.Blackboard.GetInt("i", tick.GetTree().GetID(), this.GetID())
+	var i = tick.Blackboard.GetInt("i", tick.GetTree().GetID(), tick.GetNodeScope(this))
 	if i < this.maxLoop {
 		var status = this.GetChild().Execute(tick)
 		if status == b3.SUCCESS || status == b3.FAILURE {
-			tick.Blackboard.Set("i", i+1, tick.GetTree().GetID(), this.GetID())
+			tick.Blackboard.Set("i", i+1, tick.GetTree().GetID(), tick.GetNodeScope(this))
 		}
 		return status
 	}
 
 	return b3.FAILURE
 }
+
+/**
+ * The number of executions is kept in the node memory when the node closes.
+ * @method RetainMemory
+ * @return {Boolean} Always true.
+**/
+func (this *Limiter) RetainMemory() bool {
+	return true
+}
@@ -12,7 +12,7 @@ import (
  * The MaxTime decorator limits the maximum time the node child can execute.
  * Notice that it does not interrupt the execution itself (i.e., the child
  * must be non-preemptive), it only interrupts the node after a `RUNNING`
- * status.
+ * status. The time is read from the clock of the tree.
  *
  * @module b3
  * @class MaxTime
@@ -49,8 +49,8 @@ func (this *MaxTime) Initialize(setting *BTNodeCfg) {
  * @param {Tick} tick A tick instance.
 **/
 func (this *MaxTime) OnOpen(tick *Tick) {
-	var startTime int64 = time.Now().UnixNano() / 1000000
-	tick.Blackboard.Set("startTime", startTime, tick.GetTree().GetID(), this.GetID())
+	var startTime int64 = tick.NowMilli()
+	tick.Blackboard.Set("startTime", startTime, tick.GetTree().GetID(), tick.GetNodeScope(this))
 }
 
 /**
@@ -61,14 +61,22 @@ func (this *MaxTime) OnOpen(tick *Tick) {
 **/
 func (this *MaxTime) OnTick(tick *Tick) b3.Status {
 	if this.GetChild() == nil {
-		return b3.ERROR
+		return tick.Fail(this, ErrNoChild)
 	}
-	var currTime int64 = time.Now().UnixNano() / 1000000
-	var startTime int64 = tick.Blackboard.GetInt64("startTime", tick.GetTree().GetID(), this.GetID())
+	var currTime int64 = tick.NowMilli()
+	var startTime int64 = tick.Blackboard.GetInt64("startTime", tick.GetTree().GetID(), tick.GetNodeScope(this))
 	var status = this.GetChild().Execute(tick)
+	//子节点执行期间tick被取消
+	if tick.IsCancelled() {
+		return tick.Fail(this, tick.GetContext().Err())
+	}
 	if currTime-startTime > this.maxTime {
 		return b3.FAILURE
 	}
 
+	//事件驱动时，超时的时候再tick
+	if status == b3.RUNNING {
+		tick.WakeAt(time.UnixMilli(startTime + this.maxTime + 1))
+	}
 	return status
 }
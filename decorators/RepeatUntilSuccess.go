@@ -47,7 +47,7 @@ func (this *RepeatUntilSuccess) Initialize(setting *BTNodeCfg) {
  * @param {Tick} tick A tick instance.
 **/
 func (this *RepeatUntilSuccess) OnOpen(tick *Tick) {
-	tick.Blackboard.Set("i", 0, tick.GetTree().GetID(), this.GetID())
+	tick.Blackboard.Set("i", 0, tick.GetTree().GetID(), tick.GetNodeScope(this))
 }
 
 /**
@@ -58,9 +58,9 @@ func (this *RepeatUntilSuccess) OnOpen(tick *Tick) {
 **/
 func (this *RepeatUntilSuccess) OnTick(tick *Tick) b3.Status {
 	if this.GetChild() == nil {
-		return b3.ERROR
+		return tick.Fail(this, ErrNoChild)
 	}
-	var i = tick.Blackboard.GetInt("i", tick.GetTree().GetID(), this.GetID())
+	var i = tick.Blackboard.GetInt("i", tick.GetTree().GetID(), tick.GetNodeScope(this))
 	var status = b3.ERROR
 	for this.maxLoop < 0 || i < this.maxLoop {
 		status = this.GetChild().Execute(tick)
@@ -71,6 +71,6 @@ func (this *RepeatUntilSuccess) OnTick(tick *Tick) b3.Status {
 		}
 	}
 
-	tick.Blackboard.Set("i", i, tick.GetTree().GetID(), this.GetID())
+	tick.Blackboard.Set("i", i, tick.GetTree().GetID(), tick.GetNodeScope(this))
 	return status
 }
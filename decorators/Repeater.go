@@ -47,7 +47,7 @@ func (this *Repeater) Initialize(setting *BTNodeCfg) {
  * @param {Tick} tick A tick instance.
 **/
 func (this *Repeater) OnOpen(tick *Tick) {
-	tick.Blackboard.Set("i", 0, tick.GetTree().GetID(), this.GetID())
+	tick.Blackboard.Set("i", 0, tick.GetTree().GetID(), tick.GetNodeScope(this))
 }
 
 /**
@@ -59,9 +59,9 @@ func (this *Repeater) OnOpen(tick *Tick) {
 func (this *Repeater) OnTick(tick *Tick) b3.Status {
 	//fmt.Println("tick ", this.GetTitle())
 	if this.GetChild() == nil {
-		return b3.ERROR
+		return tick.Fail(this, ErrNoChild)
 	}
-	var i = tick.Blackboard.GetInt("i", tick.GetTree().GetID(), this.GetID())
+	var i = tick.Blackboard.GetInt("i", tick.GetTree().GetID(), tick.GetNodeScope(this))
 	var status = b3.SUCCESS
 	for this.maxLoop < 0 || i < this.maxLoop {
 		status = this.GetChild().Execute(tick)
@@ -71,6 +71,6 @@ func (this *Repeater) OnTick(tick *Tick) b3.Status {
 			break
 		}
 	}
-	tick.Blackboard.Set("i", i, tick.GetTree().GetID(), this.GetID())
+	tick.Blackboard.Set("i", i, tick.GetTree().GetID(), tick.GetNodeScope(this))
 	return status
 }
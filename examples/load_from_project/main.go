@@ -23,20 +23,20 @@ func main() {
 	maps := b3.NewRegisterStructMaps()
 	maps.Register("Log", new(LogTest))
 
-	var firstTree *BehaviorTree
 	//载入
-	for _, v := range projectConfig.Trees {
-		tree := CreateBevTreeFromConfig(&v, maps)
-		tree.Print()
-		if firstTree == nil {
-			firstTree = tree
-		}
+	project, err := NewProject(projectConfig, maps)
+	if err != nil {
+		fmt.Println("NewProject err:", err)
+		return
 	}
+	//执行工程中选中的树(selectedTree)，不再是文件中的第一个树
+	tree := project.GetEntry()
+	tree.Print()
 
 	//输入板
 	board := NewBlackboard()
 	//循环每一帧
 	for i := 0; i < 5; i++ {
-		firstTree.Tick(i, board)
+		tree.Tick(i, board)
 	}
 }
@@ -23,20 +23,21 @@ func main() {
 	maps := b3.NewRegisterStructMaps()
 	maps.Register("Log", new(LogTest))
 
-	var firstTree *BehaviorTree
-	//载入
-	for _, v := range projectConfig.Data.Trees {
-		tree := CreateBevTreeFromConfig(&v, maps)
+	//载入，子树在工程中查找
+	project, err := NewRawProject(projectConfig, maps)
+	if err != nil {
+		fmt.Println("NewRawProject err:", err)
+		return
+	}
+	for _, tree := range project.GetTrees() {
 		tree.Print()
-		if firstTree == nil {
-			firstTree = tree
-		}
 	}
+	tree := project.GetEntry()
 
 	//输入板
 	board := NewBlackboard()
 	//循环每一帧
 	for i := 0; i < 5; i++ {
-		firstTree.Tick(i, board)
+		tree.Tick(i, board)
 	}
 }
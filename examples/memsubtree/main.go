@@ -10,29 +10,19 @@ import (
 	. "github.com/magicsea/behavior3go/core"
 	. "github.com/magicsea/behavior3go/examples/share"
 	. "github.com/magicsea/behavior3go/loader"
-	"sync"
 	"time"
 )
 
-//所有的树管理
-var mapTreesByID = sync.Map{}
 var maps = b3.NewRegisterStructMaps()
+
+// 工程中选中的是子树，主树按配置的ID取
+const mainTreeID = "1f00ce39-153e-4dc1-84be-e94521c60548"
+
 func init() {
 	//自定义节点注册
 	maps.Register("Log", new(LogTest))
 	maps.Register("SetValue", new(SetValue))
 	maps.Register("IsValue", new(IsValue))
-
-
-	//获取子树的方法
-	SetSubTreeLoadFunc(func(id string) *BehaviorTree {
-		//println("==>load subtree:",id)
-		t, ok := mapTreesByID.Load(id)
-		if ok {
-			return t.(*BehaviorTree)
-		}
-		return nil
-	})
 }
 
 func main() {
@@ -42,24 +32,26 @@ func main() {
 		return
 	}
 
-	var firstTree *BehaviorTree
-	//载入
-	for _, v := range projectConfig.Data.Trees {
-		tree := CreateBevTreeFromConfig(&v, maps)
+	//载入，子树在工程中查找
+	project, err := NewRawProject(projectConfig, maps)
+	if err != nil {
+		fmt.Println("NewRawProject err:", err)
+		return
+	}
+	for _, tree := range project.GetTrees() {
 		tree.Print()
-		//保存到树管理
-		println("==>store subtree:",v.ID)
-		mapTreesByID.Store(v.ID, tree)
-		if firstTree == nil {
-			firstTree = tree
-		}
+	}
+	tree := project.GetTree(mainTreeID)
+	if tree == nil {
+		fmt.Println("tree not found:", mainTreeID)
+		return
 	}
 
 	//输入板
 	board := NewBlackboard()
 	//循环每一帧
 	for i := 0; i < 100; i++ {
-		firstTree.Tick(i, board)
-		time.Sleep(time.Millisecond*100)
+		tree.Tick(i, board)
+		time.Sleep(time.Millisecond * 100)
 	}
 }
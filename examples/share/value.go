@@ -23,7 +23,7 @@ func (this *SetValue) Initialize(setting *BTNodeCfg) {
 }
 
 func (this *SetValue) OnTick(tick *Tick) b3.Status {
-	tick.Blackboard.SetMem(this.key,this.value)
+	tick.SetMem(this.key,this.value)
 	return b3.SUCCESS
 }
 
@@ -42,7 +42,7 @@ func (this *IsValue) Initialize(setting *BTNodeCfg) {
 }
 
 func (this *IsValue) OnTick(tick *Tick) b3.Status {
-	v := tick.Blackboard.GetInt(this.key,"","")
+	v, _ := ToNumber[int](tick.GetMem(this.key))
 	if v==this.value {
 		return b3.SUCCESS
 	}
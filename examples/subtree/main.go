@@ -10,24 +10,8 @@ import (
 	. "github.com/magicsea/behavior3go/core"
 	. "github.com/magicsea/behavior3go/examples/share"
 	. "github.com/magicsea/behavior3go/loader"
-	"sync"
 )
 
-//所有的树管理
-var mapTreesByID = sync.Map{}
-
-func init() {
-	//获取子树的方法
-	SetSubTreeLoadFunc(func(id string) *BehaviorTree {
-		println("==>load subtree:",id)
-		t, ok := mapTreesByID.Load(id)
-		if ok {
-			return t.(*BehaviorTree)
-		}
-		return nil
-	})
-}
-
 func main() {
 	projectConfig, ok := LoadRawProjectCfg("example.b3")
 	if !ok {
@@ -39,23 +23,21 @@ func main() {
 	maps := b3.NewRegisterStructMaps()
 	maps.Register("Log", new(LogTest))
 
-	var firstTree *BehaviorTree
-	//载入
-	for _, v := range projectConfig.Data.Trees {
-		tree := CreateBevTreeFromConfig(&v, maps)
+	//载入，子树在工程中查找
+	project, err := NewRawProject(projectConfig, maps)
+	if err != nil {
+		fmt.Println("NewRawProject err:", err)
+		return
+	}
+	for _, tree := range project.GetTrees() {
 		tree.Print()
-		//保存到树管理
-		println("==>store subtree:",v.ID)
-		mapTreesByID.Store(v.ID, tree)
-		if firstTree == nil {
-			firstTree = tree
-		}
 	}
+	tree := project.GetEntry()
 
 	//输入板
 	board := NewBlackboard()
 	//循环每一帧
 	for i := 0; i < 5; i++ {
-		firstTree.Tick(i, board)
+		tree.Tick(i, board)
 	}
 }
@@ -10,12 +10,9 @@ import (
 	. "github.com/magicsea/behavior3go/core"
 	. "github.com/magicsea/behavior3go/examples/share"
 	. "github.com/magicsea/behavior3go/loader"
-	"sync"
 	"time"
 )
 
-// 所有的树管理
-var mapTreesByID = sync.Map{}
 var maps = b3.NewRegisterStructMaps()
 
 func init() {
@@ -23,16 +20,6 @@ func init() {
 	maps.Register("Log", new(LogTest))
 	maps.Register("SetValue", new(SetValue))
 	maps.Register("IsValue", new(IsValue))
-
-	//获取子树的方法
-	SetSubTreeLoadFunc(func(id string) *BehaviorTree {
-		//println("==>load subtree:",id)
-		t, ok := mapTreesByID.Load(id)
-		if ok {
-			return t.(*BehaviorTree)
-		}
-		return nil
-	})
 }
 
 func main() {
@@ -42,22 +29,22 @@ func main() {
 		return
 	}
 
-	var firstTree *BehaviorTree
-	//载入
-	for _, v := range projectConfig.Data.Trees {
-		tree := CreateBevTreeFromConfig(&v, maps)
+	//载入，子树在工程中查找
+	project, err := NewRawProject(projectConfig, maps)
+	if err != nil {
+		fmt.Println("NewRawProject err:", err)
+		return
+	}
+	for _, tree := range project.GetTrees() {
 		tree.Print()
-		mapTreesByID.Store(v.ID, tree)
-		if firstTree == nil {
-			firstTree = tree
-		}
 	}
+	tree := project.GetEntry()
 	time.Sleep(time.Second)
 	//输入板
 	board := NewBlackboard()
 	//循环每一帧
 	for i := 0; i < 40; i++ {
-		firstTree.Tick(i, board)
+		tree.Tick(i, board)
 		time.Sleep(time.Millisecond * 100)
 	}
 }
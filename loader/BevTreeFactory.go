@@ -43,3 +43,11 @@ func CreateBevTreeFromConfig(config *BTTreeCfg, extMap *b3.RegisterStructMaps) *
 	tree.Load(config, baseMaps, extMap)
 	return tree
 }
+
+//加载树，schema为工程声明的黑板key
+func CreateBevTreeFromConfigWithSchema(config *BTTreeCfg, extMap *b3.RegisterStructMaps, schema *Schema) *BehaviorTree {
+	baseMaps := createBaseStructMaps()
+	tree := NewBeTree()
+	tree.LoadWithSchema(config, baseMaps, extMap, schema)
+	return tree
+}
@@ -0,0 +1,237 @@
+package loader
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	b3 "github.com/magicsea/behavior3go"
+	. "github.com/magicsea/behavior3go/config"
+	. "github.com/magicsea/behavior3go/core"
+)
+
+/**
+ * The trees of a project, loaded together. The SubTree nodes of the trees
+ * find their tree in the project, by tree ID or title, instead of the
+ * global `SetSubTreeLoadFunc`, so several projects can be loaded in one
+ * process. The blackboard keys declared in the properties of the project
+ * are declared in every tree.
+ *
+ *     projectConfig, _ := LoadRawProjectCfg("example.b3")
+ *     project, err := NewRawProject(projectConfig, maps)
+ *     if err != nil {
+ *         ...
+ *     }
+ *     project.GetEntry().Tick(target, board)
+ *
+ * @module b3
+ * @class Project
+**/
+type Project struct {
+	id      string
+	schema  *Schema
+	trees   []*BehaviorTree
+	byID    map[string]*BehaviorTree
+	byTitle map[string]*BehaviorTree
+	entry   *BehaviorTree
+}
+
+/**
+ * The options of a project, see `NewProjectWith`.
+ *
+ * - **MaxSubTreeDepth** The maximum number of nested SubTree nodes in a
+ *   traversal, see `BehaviorTree.SetMaxSubTreeDepth`. When set, the
+ *   subtrees may reference each other in a cycle, for recursive behaviors;
+ *   when 0, a cycle is an error and the default limit applies.
+**/
+type ProjectOptions struct {
+	MaxSubTreeDepth int
+}
+
+/**
+ * Loads all the trees of a project. The entry tree is the selected tree of
+ * the project, or the first one if none is selected.
+ *
+ * @method NewProject
+ * @param {BTProjectCfg} config The project.
+ * @param {RegisterStructMaps} extMap The custom nodes.
+ * @return {Project} The project.
+ * @return {error} A duplicate tree ID, a missing selected tree, an invalid
+ *                 declaration, a tree which cannot be loaded, a SubTree
+ *                 node whose tree is not in the project or a cycle of
+ *                 subtrees.
+**/
+func NewProject(config *BTProjectCfg, extMap *b3.RegisterStructMaps) (*Project, error) {
+	return NewProjectWith(config, extMap, ProjectOptions{})
+}
+
+//加载工程，带选项，见NewProject
+func NewProjectWith(config *BTProjectCfg, extMap *b3.RegisterStructMaps, options ProjectOptions) (*Project, error) {
+	var schema, err = ParseSchema(config.Properties)
+	if err != nil {
+		return nil, fmt.Errorf("project %s: %v", config.ID, err)
+	}
+	var project = &Project{
+		id:      config.ID,
+		schema:  schema,
+		byID:    make(map[string]*BehaviorTree, len(config.Trees)),
+		byTitle: make(map[string]*BehaviorTree, len(config.Trees)),
+	}
+	for i := range config.Trees {
+		var cfg = &config.Trees[i]
+		if _, ok := project.byID[cfg.ID]; ok {
+			return nil, fmt.Errorf("project %s: duplicate tree %s", config.ID, cfg.ID)
+		}
+		var tree, err = loadProjectTree(cfg, extMap, schema)
+		if err != nil {
+			return nil, fmt.Errorf("project %s: %v", config.ID, err)
+		}
+		tree.SetSubTreeLoader(project.GetTree)
+		tree.SetMaxSubTreeDepth(options.MaxSubTreeDepth)
+		project.trees = append(project.trees, tree)
+		project.byID[cfg.ID] = tree
+		//同名的树，按标题查找时取第一个
+		if _, ok := project.byTitle[cfg.Title]; !ok && len(cfg.Title) > 0 {
+			project.byTitle[cfg.Title] = tree
+		}
+	}
+
+	if err := project.checkSubTrees(config, options.MaxSubTreeDepth > 0); err != nil {
+		return nil, fmt.Errorf("project %s: %v", config.ID, err)
+	}
+
+	if len(config.Select) > 0 {
+		if project.entry = project.byID[config.Select]; project.entry == nil {
+			return nil, fmt.Errorf("project %s: selected tree %s not found", config.ID, config.Select)
+		}
+	} else if len(project.trees) > 0 {
+		project.entry = project.trees[0]
+	}
+	return project, nil
+}
+
+//检查SubTree节点引用的树都存在，没有循环引用
+func (this *Project) checkSubTrees(config *BTProjectCfg, allowCycles bool) error {
+	var errs []string
+	var edges = make(map[string][]string, len(config.Trees))
+	var treeIDs = make(map[*BehaviorTree]string, len(this.byID))
+	for id, tree := range this.byID {
+		treeIDs[tree] = id
+	}
+	for i := range config.Trees {
+		var cfg = &config.Trees[i]
+		var ids = make([]string, 0, len(cfg.Nodes))
+		for id, spec := range cfg.Nodes {
+			if spec.Category == "tree" {
+				ids = append(ids, id)
+			}
+		}
+		sort.Strings(ids)
+		for _, id := range ids {
+			var spec = cfg.Nodes[id]
+			var target = this.GetTree(spec.Name)
+			if target == nil {
+				errs = append(errs, fmt.Sprintf("tree %s(%s) node %s: %v: %s", cfg.ID, cfg.Title, id, ErrSubTreeNotFound, spec.Name))
+				continue
+			}
+			edges[cfg.ID] = append(edges[cfg.ID], treeIDs[target])
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("%s", strings.Join(errs, "; "))
+	}
+	if allowCycles {
+		return nil
+	}
+
+	//深度优先，路径上再次遇到的树构成循环
+	const (
+		visiting = 1
+		done     = 2
+	)
+	var state = make(map[string]int, len(config.Trees))
+	var path []string
+	var visit func(id string) []string
+	visit = func(id string) []string {
+		state[id] = visiting
+		path = append(path, id)
+		for _, next := range edges[id] {
+			switch state[next] {
+			case visiting:
+				for i, p := range path {
+					if p == next {
+						return append(append([]string{}, path[i:]...), next)
+					}
+				}
+			case 0:
+				if cycle := visit(next); cycle != nil {
+					return cycle
+				}
+			}
+		}
+		path = path[:len(path)-1]
+		state[id] = done
+		return nil
+	}
+	for i := range config.Trees {
+		if state[config.Trees[i].ID] != 0 {
+			continue
+		}
+		if cycle := visit(config.Trees[i].ID); cycle != nil {
+			var titles = make([]string, len(cycle))
+			for j, id := range cycle {
+				titles[j] = fmt.Sprintf("%s(%s)", id, this.byID[id].GetTitile())
+			}
+			return fmt.Errorf("subtree cycle %s", strings.Join(titles, " -> "))
+		}
+	}
+	return nil
+}
+
+//加载原生工程，见NewProject
+func NewRawProject(config *RawProjectCfg, extMap *b3.RegisterStructMaps) (*Project, error) {
+	return NewProject(&config.Data, extMap)
+}
+
+//加载树，panic转为error
+func loadProjectTree(config *BTTreeCfg, extMap *b3.RegisterStructMaps, schema *Schema) (tree *BehaviorTree, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("tree %s(%s): %v", config.ID, config.Title, r)
+		}
+	}()
+	return CreateBevTreeFromConfigWithSchema(config, extMap, schema), nil
+}
+
+func (this *Project) GetID() string {
+	return this.id
+}
+
+/**
+ * The tree to tick: the selected tree of the project, or the first one.
+ * nil if the project has no tree.
+**/
+func (this *Project) GetEntry() *BehaviorTree {
+	return this.entry
+}
+
+/**
+ * The tree of the given config ID, or else of the given title. nil if not
+ * found. The SubTree nodes of the project find their tree with it.
+**/
+func (this *Project) GetTree(idOrTitle string) *BehaviorTree {
+	if tree, ok := this.byID[idOrTitle]; ok {
+		return tree
+	}
+	return this.byTitle[idOrTitle]
+}
+
+//所有的树，工程中的顺序
+func (this *Project) GetTrees() []*BehaviorTree {
+	return append([]*BehaviorTree{}, this.trees...)
+}
+
+//工程声明的黑板key
+func (this *Project) GetSchema() *Schema {
+	return this.schema
+}
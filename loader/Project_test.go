@@ -0,0 +1,120 @@
+package loader
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	b3 "github.com/magicsea/behavior3go"
+	. "github.com/magicsea/behavior3go/config"
+	. "github.com/magicsea/behavior3go/core"
+)
+
+//主树通过标题引用子树child，子树只有一个动作
+func createProjectCfg(id, action string) *BTProjectCfg {
+	return &BTProjectCfg{
+		ID:     id,
+		Select: "main",
+		Trees: []BTTreeCfg{
+			{ID: "child", Title: "child", Root: "act", Nodes: map[string]BTNodeCfg{
+				"act": {Id: "act", Name: action, Category: b3.ACTION},
+			}},
+			{ID: "main", Title: "main", Root: "sub", Nodes: map[string]BTNodeCfg{
+				"sub": {Id: "sub", Name: "child", Category: "tree"},
+			}},
+		},
+	}
+}
+
+func TestProject(t *testing.T) {
+	var ok, err = NewProject(createProjectCfg("ok", "Succeeder"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fail, err := NewRawProject(&RawProjectCfg{Data: *createProjectCfg("fail", "Failer")}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the two projects resolve their own child tree
+	if status := ok.GetEntry().Tick(0, NewBlackboard()); status != b3.SUCCESS {
+		t.Error("ok status:", status)
+	}
+	if status := fail.GetEntry().Tick(0, NewBlackboard()); status != b3.FAILURE {
+		t.Error("fail status:", status)
+	}
+	if ok.GetTree("child") == fail.GetTree("child") || len(ok.GetTrees()) != 2 {
+		t.Error("trees shared")
+	}
+}
+
+func TestProjectErrors(t *testing.T) {
+	var missing = createProjectCfg("missing", "Succeeder")
+	missing.Select = "other"
+	var duplicate = createProjectCfg("duplicate", "Succeeder")
+	duplicate.Trees[1].ID = "child"
+	var unknown = createProjectCfg("unknown", "Attack")
+	var schema = createProjectCfg("schema", "Succeeder")
+	schema.Properties = map[string]interface{}{"bb.hp": "integer"}
+
+	for _, c := range []struct {
+		config *BTProjectCfg
+		err    string
+	}{
+		{missing, "selected tree other not found"},
+		{duplicate, "duplicate tree child"},
+		{unknown, "tree child(child)"},
+		{schema, "invalid type integer"},
+	} {
+		if _, err := NewProject(c.config, nil); err == nil || !strings.Contains(err.Error(), c.err) {
+			t.Error(c.config.ID, err)
+		}
+	}
+}
+
+//a和b互相引用
+func createCycleCfg() *BTProjectCfg {
+	return &BTProjectCfg{
+		ID:     "cycle",
+		Select: "a",
+		Trees: []BTTreeCfg{
+			{ID: "a", Title: "a", Root: "seq", Nodes: map[string]BTNodeCfg{
+				"seq": {Id: "seq", Name: "Sequence", Category: b3.COMPOSITE, Children: []string{"ok", "b"}},
+				"ok":  {Id: "ok", Name: "Succeeder", Category: b3.ACTION},
+				"b":   {Id: "b", Name: "b", Category: "tree"},
+			}},
+			{ID: "b", Title: "b", Root: "a", Nodes: map[string]BTNodeCfg{
+				"a": {Id: "a", Name: "a", Category: "tree"},
+			}},
+		},
+	}
+}
+
+func TestProjectSubTreeGraph(t *testing.T) {
+	var missing = createProjectCfg("missing", "Succeeder")
+	missing.Trees[1].Nodes["sub"] = BTNodeCfg{Id: "sub", Name: "nobody", Category: "tree"}
+	if _, err := NewProject(missing, nil); err == nil || !strings.Contains(err.Error(), "node sub: subtree not found: nobody") {
+		t.Error("missing subtree:", err)
+	}
+	if _, err := NewProject(createCycleCfg(), nil); err == nil || !strings.Contains(err.Error(), "subtree cycle a(a) -> b(b) -> a(a)") {
+		t.Error("cycle:", err)
+	}
+
+	// allowed under a depth limit, stopped at runtime
+	project, err := NewProjectWith(createCycleCfg(), nil, ProjectOptions{MaxSubTreeDepth: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var tree = project.GetEntry()
+	var board = NewBlackboard()
+	if status, err := tree.TickE(0, board); status != b3.ERROR || !errors.Is(err, ErrSubTreeDepth) {
+		t.Error("depth guard:", status, err)
+	}
+	snapshot, err := board.Snapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data := snapshot.Trees[0]; data.TraversalDepth != 4 || data.DepthLimitHits != 1 {
+		t.Error("traversal:", data.TraversalDepth, data.DepthLimitHits)
+	}
+}